@@ -0,0 +1,157 @@
+// Package ast declares the types used to represent the syntax tree of a parsed JSONPath
+// query (RFC 9535), as returned by JSONPath.AST.
+//
+// The tree is a copy of the parser's own: changing it has no effect on the query it was
+// taken from.
+package ast
+
+// Node is any node of the syntax tree.
+type Node interface {
+	node()
+}
+
+// Selector is a selector of a segment: *NameSelector, *WildcardSelector, *IndexSelector,
+// *SliceSelector or *FilterSelector.
+type Selector interface {
+	Node
+	selector()
+}
+
+// Expr is a logical expression of a filter: *OrExpr, *AndExpr, *ParenExpr,
+// *ComparisonExpr or *TestExpr.
+type Expr interface {
+	Node
+	expr()
+}
+
+// Query is a query: the root query, an absolute query ($...) within a filter, or a
+// relative one (@...).
+type Query struct {
+	// Relative is true for a query starting at the current node, @.
+	Relative bool
+	Segments []*Segment
+}
+
+type SegmentKind int
+
+const (
+	ChildSegment        SegmentKind = iota // .name, .* or [selectors]
+	DescendantSegment                      // ..name, ..* or ..[selectors]
+	PropertyNameSegment                    // ~ (extension only)
+)
+
+// Segment is a segment of a query. A property name segment has no selectors.
+type Segment struct {
+	Kind SegmentKind
+	// Dot is true for a segment written in shorthand, as .name, .*, ..name or ..*, which
+	// then has a single *NameSelector or *WildcardSelector.
+	Dot       bool
+	Selectors []Selector
+}
+
+// NameSelector selects the member of an object with the given name.
+type NameSelector struct {
+	Name string
+}
+
+// WildcardSelector selects every member of an object or element of an array.
+type WildcardSelector struct{}
+
+// IndexSelector selects the element of an array at the given index, counting back from
+// its end if negative.
+type IndexSelector struct {
+	Index int64
+}
+
+// SliceSelector selects the elements of an array from Start to End by Step, as in
+// [start:end:step]. Bounds left out are nil.
+type SliceSelector struct {
+	Start, End, Step *int64
+}
+
+// FilterSelector selects the members or elements for which Expr holds, as in [?expr].
+type FilterSelector struct {
+	Expr Expr
+}
+
+// OrExpr holds if any of its operands does: a || b.
+type OrExpr struct {
+	Operands []Expr
+}
+
+// AndExpr holds if all of its operands do: a && b.
+type AndExpr struct {
+	Operands []Expr
+}
+
+// ParenExpr is a parenthesized expression, negated if Not: (expr) or !(expr).
+type ParenExpr struct {
+	Not  bool
+	Expr Expr
+}
+
+type ComparisonOp string
+
+const (
+	Equal              ComparisonOp = "=="
+	NotEqual           ComparisonOp = "!="
+	LessThan           ComparisonOp = "<"
+	LessThanOrEqual    ComparisonOp = "<="
+	GreaterThan        ComparisonOp = ">"
+	GreaterThanOrEqual ComparisonOp = ">="
+)
+
+// ComparisonExpr compares two values. Each side is a *Literal, a singular *Query, or a
+// *FunctionCall.
+type ComparisonExpr struct {
+	Left  Node
+	Op    ComparisonOp
+	Right Node
+}
+
+// TestExpr holds if its Operand, a *Query, selects any node, or if its Operand, a
+// *FunctionCall, returns true or any node; negated if Not.
+type TestExpr struct {
+	Not     bool
+	Operand Node
+}
+
+// FunctionCall is a call to a function extension, such as length(@.tags). Each argument is
+// a *Literal, a *Query, an Expr, or a *FunctionCall.
+type FunctionCall struct {
+	Name string
+	Args []Node
+}
+
+// Literal is a literal value: an int64, a *big.Int for integers an int64 can't hold, a
+// float64, a string, a bool, or nil for null.
+type Literal struct {
+	Value any
+}
+
+func (*Query) node()            {}
+func (*Segment) node()          {}
+func (*NameSelector) node()     {}
+func (*WildcardSelector) node() {}
+func (*IndexSelector) node()    {}
+func (*SliceSelector) node()    {}
+func (*FilterSelector) node()   {}
+func (*OrExpr) node()           {}
+func (*AndExpr) node()          {}
+func (*ParenExpr) node()        {}
+func (*ComparisonExpr) node()   {}
+func (*TestExpr) node()         {}
+func (*FunctionCall) node()     {}
+func (*Literal) node()          {}
+
+func (*NameSelector) selector()     {}
+func (*WildcardSelector) selector() {}
+func (*IndexSelector) selector()    {}
+func (*SliceSelector) selector()    {}
+func (*FilterSelector) selector()   {}
+
+func (*OrExpr) expr()         {}
+func (*AndExpr) expr()        {}
+func (*ParenExpr) expr()      {}
+func (*ComparisonExpr) expr() {}
+func (*TestExpr) expr()       {}
@@ -0,0 +1,65 @@
+package ast
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If the result
+// visitor w is not nil, Walk visits each of the children of node with w, followed by a
+// call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node in depth-first order: it starts by calling
+// v.Visit(node), and then walks each of the node's children in the order they appear in
+// the query, such as the left side of a comparison before its right side.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	switch n := node.(type) {
+	case *Query:
+		for _, segment := range n.Segments {
+			Walk(v, segment)
+		}
+	case *Segment:
+		for _, selector := range n.Selectors {
+			Walk(v, selector)
+		}
+	case *FilterSelector:
+		Walk(v, n.Expr)
+	case *OrExpr:
+		for _, operand := range n.Operands {
+			Walk(v, operand)
+		}
+	case *AndExpr:
+		for _, operand := range n.Operands {
+			Walk(v, operand)
+		}
+	case *ParenExpr:
+		Walk(v, n.Expr)
+	case *ComparisonExpr:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *TestExpr:
+		Walk(v, n.Operand)
+	case *FunctionCall:
+		for _, arg := range n.Args {
+			Walk(v, arg)
+		}
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in depth-first order, as Walk does: it starts
+// by calling f(node), and walks the node's children if f returns true, followed by a call
+// of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,452 @@
+package jsonpath
+
+import (
+	"fmt"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"math"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// Builder builds a query segment by segment, rather than by formatting its text, so that
+// the query is valid whatever the names and values in it: each name is escaped as
+// String escapes it. Root starts a query and Rel a relative query, to use in a filter.
+// Each method returns a new Builder, leaving the one it is called on as it was.
+//
+// Methods panic only when the structure of the query is invalid, whatever its names and
+// values: when a query that may select more than one node is compared, or a relative
+// query is built on its own.
+//
+//	jsonpath.Root().Child("paths").Name("/pets/{id}").Wildcard().
+//		Filter(jsonpath.Rel("x-internal").Exists()).Build()
+type Builder struct {
+	relative bool
+	segments []*segment
+}
+
+// Root starts a query at the root node, $.
+func Root() *Builder {
+	return &Builder{}
+}
+
+// Rel starts a query at the current node of a filter, @, followed by a child segment for
+// each of names.
+func Rel(names ...string) *Builder {
+	b := &Builder{relative: true}
+	for _, name := range names {
+		b = b.Child(name)
+	}
+	return b
+}
+
+// Build returns the query. It panics if the query is relative.
+func (b *Builder) Build(opts ...config.Option) *JSONPath {
+	if b.relative {
+		panic(fmt.Sprintf("jsonpath: cannot build the relative query %s on its own", b))
+	}
+	path, err := NewPath(b.String(), opts...)
+	if err != nil {
+		panic(fmt.Sprintf("jsonpath: built an invalid query: %v", err))
+	}
+	return path
+}
+
+func (b *Builder) String() string {
+	builder := strings.Builder{}
+	if b.relative {
+		builder.WriteString("@")
+	} else {
+		builder.WriteString("$")
+	}
+	for _, s := range b.segments {
+		builder.WriteString(s.ToString())
+	}
+	return builder.String()
+}
+
+func (b *Builder) with(s *segment) *Builder {
+	return &Builder{relative: b.relative, segments: append(b.segments[:len(b.segments):len(b.segments)], s)}
+}
+
+// Child selects the member with the given name, as .name if the name can be written so,
+// and as ['name'] otherwise.
+func (b *Builder) Child(name string) *Builder {
+	return b.with(&segment{kind: segmentKindChild, child: childSegment(name)})
+}
+
+// Name selects the members with the given names, as ['name', ...].
+func (b *Builder) Name(name string, more ...string) *Builder {
+	return b.with(&segment{kind: segmentKindChild, child: nameSegment(name, more)})
+}
+
+// Index selects the array elements at the given indices, as [index, ...].
+func (b *Builder) Index(index int, more ...int) *Builder {
+	return b.with(&segment{kind: segmentKindChild, child: indexSegment(index, more)})
+}
+
+// Slice selects the array elements from start to end by step, as [start:end:step]. Bounds
+// left out are nil.
+func (b *Builder) Slice(start, end, step *int) *Builder {
+	return b.with(&segment{kind: segmentKindChild, child: sliceSegment(start, end, step)})
+}
+
+// Wildcard selects every member or element, as .*.
+func (b *Builder) Wildcard() *Builder {
+	return b.with(&segment{kind: segmentKindChild, child: &innerSegment{kind: segmentDotWildcard}})
+}
+
+// Filter selects the members or elements for which expr holds, as [?expr].
+func (b *Builder) Filter(expr Expr) *Builder {
+	return b.with(&segment{kind: segmentKindChild, child: filterSegment(expr)})
+}
+
+// PropertyName selects the keys of the members selected so far, as ~. The query must be
+// built with config.WithPropertyNameExtension.
+func (b *Builder) PropertyName() *Builder {
+	return b.with(&segment{kind: segmentKindProperyName})
+}
+
+// Descendants starts a descendant segment: the selector that follows selects from the
+// current nodes and all of their descendants, as ..name or ..[selectors].
+func (b *Builder) Descendants() *DescendantBuilder {
+	return &DescendantBuilder{b}
+}
+
+// DescendantBuilder adds a descendant segment to a query, with the selector of its
+// methods, which are those of Builder.
+type DescendantBuilder struct {
+	b *Builder
+}
+
+func (d *DescendantBuilder) with(inner *innerSegment) *Builder {
+	return d.b.with(&segment{kind: segmentKindDescendant, descendant: inner})
+}
+
+// Child is like Builder.Child, as ..name or ..['name'].
+func (d *DescendantBuilder) Child(name string) *Builder {
+	return d.with(childSegment(name))
+}
+
+// Name is like Builder.Name, as ..['name', ...].
+func (d *DescendantBuilder) Name(name string, more ...string) *Builder {
+	return d.with(nameSegment(name, more))
+}
+
+// Index is like Builder.Index, as ..[index, ...].
+func (d *DescendantBuilder) Index(index int, more ...int) *Builder {
+	return d.with(indexSegment(index, more))
+}
+
+// Slice is like Builder.Slice, as ..[start:end:step].
+func (d *DescendantBuilder) Slice(start, end, step *int) *Builder {
+	return d.with(sliceSegment(start, end, step))
+}
+
+// Wildcard is like Builder.Wildcard, as ..*.
+func (d *DescendantBuilder) Wildcard() *Builder {
+	return d.with(&innerSegment{kind: segmentDotWildcard})
+}
+
+// Filter is like Builder.Filter, as ..[?expr].
+func (d *DescendantBuilder) Filter(expr Expr) *Builder {
+	return d.with(filterSegment(expr))
+}
+
+func childSegment(name string) *innerSegment {
+	if isShorthandName(name) {
+		return &innerSegment{kind: segmentDotMemberName, dotName: name}
+	}
+	return nameSegment(name, nil)
+}
+
+func nameSegment(name string, more []string) *innerSegment {
+	inner := &innerSegment{kind: segmentLongHand}
+	for _, name := range append([]string{name}, more...) {
+		inner.selectors = append(inner.selectors, &selector{kind: selectorSubKindName, name: name})
+	}
+	return inner
+}
+
+func indexSegment(index int, more []int) *innerSegment {
+	inner := &innerSegment{kind: segmentLongHand}
+	for _, index := range append([]int{index}, more...) {
+		inner.selectors = append(inner.selectors, &selector{kind: selectorSubKindArrayIndex, index: int64(index)})
+	}
+	return inner
+}
+
+func sliceSegment(start, end, step *int) *innerSegment {
+	bound := func(b *int) *int64 {
+		if b == nil {
+			return nil
+		}
+		value := int64(*b)
+		return &value
+	}
+	s := &selector{kind: selectorSubKindArraySlice, slice: &slice{start: bound(start), end: bound(end), step: bound(step)}}
+	return &innerSegment{kind: segmentLongHand, selectors: []*selector{s}}
+}
+
+func filterSegment(expr Expr) *innerSegment {
+	s := &selector{kind: selectorSubKindFilter, filter: &filterSelector{expression: expr.expr}}
+	return &innerSegment{kind: segmentLongHand, selectors: []*selector{s}}
+}
+
+// isShorthandName reports whether name can be written as a member-name-shorthand, .name
+// (RFC 9535 §2.5.1.1), and read back as the same name. The words the tokenizer reads as
+// literals and function names can't.
+func isShorthandName(name string) bool {
+	switch name {
+	case "", "true", "false", "null", "length", "count", "match", "search", "value":
+		return false
+	}
+	if !utf8.ValidString(name) || '0' <= name[0] && name[0] <= '9' {
+		return false
+	}
+	for _, r := range name {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_' || r >= 0x80) {
+			return false
+		}
+	}
+	return true
+}
+
+// singular returns the query as a singular query, or panics if it isn't one.
+func (b *Builder) singular() *singularQuery {
+	for _, s := range b.segments {
+		if s.kind != segmentKindChild || s.child.kind == segmentDotWildcard ||
+			s.child.kind == segmentLongHand && (len(s.child.selectors) != 1 ||
+				s.child.selectors[0].kind != selectorSubKindName && s.child.selectors[0].kind != selectorSubKindArrayIndex) {
+			panic(fmt.Sprintf("jsonpath: %s is not a singular query, so it can't be compared", b))
+		}
+	}
+	if b.relative {
+		return &singularQuery{relQuery: &relQuery{segments: b.segments}}
+	}
+	return &singularQuery{absQuery: &absQuery{segments: b.segments}}
+}
+
+func (b *Builder) filterQuery() *filterQuery {
+	if b.relative {
+		return &filterQuery{relQuery: &relQuery{segments: b.segments}}
+	}
+	return &filterQuery{jsonPathQuery: &jsonPathAST{segments: b.segments}}
+}
+
+// Expr is a filter expression, for Builder.Filter.
+type Expr struct {
+	expr *logicalOrExpr
+}
+
+func (e Expr) String() string {
+	return e.expr.ToString()
+}
+
+func basicExprOf(basic *basicExpr) Expr {
+	return Expr{&logicalOrExpr{expressions: []*logicalAndExpr{{expressions: []*basicExpr{basic}}}}}
+}
+
+// Exists holds if the query selects any node, as @.name.
+func (b *Builder) Exists() Expr {
+	return basicExprOf(&basicExpr{testExpr: &testExpr{filterQuery: b.filterQuery()}})
+}
+
+// Not negates expr, as !(expr).
+func Not(expr Expr) Expr {
+	return basicExprOf(&basicExpr{parenExpr: &parenExpr{not: true, expr: expr.expr}})
+}
+
+// And holds if all of exprs do, as a && b. An expression with || is parenthesized.
+func And(exprs ...Expr) Expr {
+	and := &logicalAndExpr{}
+	for _, e := range exprs {
+		if len(e.expr.expressions) == 1 {
+			and.expressions = append(and.expressions, e.expr.expressions[0].expressions...)
+		} else {
+			and.expressions = append(and.expressions, &basicExpr{parenExpr: &parenExpr{expr: e.expr}})
+		}
+	}
+	return Expr{&logicalOrExpr{expressions: []*logicalAndExpr{and}}}
+}
+
+// Or holds if any of exprs does, as a || b.
+func Or(exprs ...Expr) Expr {
+	or := &logicalOrExpr{}
+	for _, e := range exprs {
+		or.expressions = append(or.expressions, e.expr.expressions...)
+	}
+	return Expr{or}
+}
+
+// Match holds if the value the query selects is a string matching the I-Regexp pattern
+// as a whole, as match(@.name, 'pattern'). It panics if the query isn't singular.
+func (b *Builder) Match(pattern string) Expr {
+	return b.regexFunction(functionTypeMatch, pattern)
+}
+
+// Search is like Match, but holds if the pattern matches any part of the string, as
+// search(@.name, 'pattern').
+func (b *Builder) Search(pattern string) Expr {
+	return b.regexFunction(functionTypeSearch, pattern)
+}
+
+func (b *Builder) regexFunction(funcType functionType, pattern string) Expr {
+	query := b.singular()
+	args := []*functionArgument{
+		{filterQuery: &filterQuery{relQuery: query.relQuery, jsonPathQuery: (*jsonPathAST)(query.absQuery)}},
+		{literal: &literal{string: &pattern}},
+	}
+	return basicExprOf(&basicExpr{testExpr: &testExpr{functionExpr: &functionExpr{funcType: funcType, args: args}}})
+}
+
+// Function is a call of a function returning a value, to compare.
+type Function struct {
+	expr *functionExpr
+}
+
+// Length returns the length of the string, array or object the query selects, as
+// length(@.name). It panics if the query isn't singular.
+func (b *Builder) Length() Function {
+	query := b.singular()
+	arg := &functionArgument{filterQuery: &filterQuery{relQuery: query.relQuery, jsonPathQuery: (*jsonPathAST)(query.absQuery)}}
+	return Function{&functionExpr{funcType: functionTypeLength, args: []*functionArgument{arg}}}
+}
+
+// Count returns the number of nodes the query selects, as count(@.name).
+func (b *Builder) Count() Function {
+	return Function{&functionExpr{funcType: functionTypeCount, args: []*functionArgument{{filterQuery: b.filterQuery()}}}}
+}
+
+// Value returns the value of the node the query selects, if it selects exactly one, as
+// value(@..name).
+func (b *Builder) Value() Function {
+	return Function{&functionExpr{funcType: functionTypeValue, args: []*functionArgument{{filterQuery: b.filterQuery()}}}}
+}
+
+// Eq holds if the value the query selects equals value: a string, a number, a bool, nil
+// for null, a singular *Builder, or a Function. It panics if either query isn't singular.
+func (b *Builder) Eq(value any) Expr {
+	return compare(&comparable{singularQuery: b.singular()}, equalTo, value)
+}
+
+// Ne is like Eq, for !=.
+func (b *Builder) Ne(value any) Expr {
+	return compare(&comparable{singularQuery: b.singular()}, notEqualTo, value)
+}
+
+// Lt is like Eq, for <.
+func (b *Builder) Lt(value any) Expr {
+	return compare(&comparable{singularQuery: b.singular()}, lessThan, value)
+}
+
+// Le is like Eq, for <=.
+func (b *Builder) Le(value any) Expr {
+	return compare(&comparable{singularQuery: b.singular()}, lessThanEqualTo, value)
+}
+
+// Gt is like Eq, for >.
+func (b *Builder) Gt(value any) Expr {
+	return compare(&comparable{singularQuery: b.singular()}, greaterThan, value)
+}
+
+// Ge is like Eq, for >=.
+func (b *Builder) Ge(value any) Expr {
+	return compare(&comparable{singularQuery: b.singular()}, greaterThanEqualTo, value)
+}
+
+// Eq is like Builder.Eq, comparing what the function returns.
+func (f Function) Eq(value any) Expr {
+	return compare(&comparable{functionExpr: f.expr}, equalTo, value)
+}
+
+// Ne is like Builder.Ne, comparing what the function returns.
+func (f Function) Ne(value any) Expr {
+	return compare(&comparable{functionExpr: f.expr}, notEqualTo, value)
+}
+
+// Lt is like Builder.Lt, comparing what the function returns.
+func (f Function) Lt(value any) Expr {
+	return compare(&comparable{functionExpr: f.expr}, lessThan, value)
+}
+
+// Le is like Builder.Le, comparing what the function returns.
+func (f Function) Le(value any) Expr {
+	return compare(&comparable{functionExpr: f.expr}, lessThanEqualTo, value)
+}
+
+// Gt is like Builder.Gt, comparing what the function returns.
+func (f Function) Gt(value any) Expr {
+	return compare(&comparable{functionExpr: f.expr}, greaterThan, value)
+}
+
+// Ge is like Builder.Ge, comparing what the function returns.
+func (f Function) Ge(value any) Expr {
+	return compare(&comparable{functionExpr: f.expr}, greaterThanEqualTo, value)
+}
+
+func compare(left *comparable, op comparisonOperator, value any) Expr {
+	right := comparableOf(value)
+	return basicExprOf(&basicExpr{comparisonExpr: &comparisonExpr{left: left, op: op, right: right}})
+}
+
+// comparableOf returns value as the operand of a comparison.
+func comparableOf(value any) *comparable {
+	switch v := value.(type) {
+	case *Builder:
+		return &comparable{singularQuery: v.singular()}
+	case Function:
+		return &comparable{functionExpr: v.expr}
+	}
+	lit, ok := literalOf(value)
+	if !ok {
+		panic(fmt.Sprintf("jsonpath: cannot compare with %v (%T)", value, value))
+	}
+	return &comparable{literal: &lit}
+}
+
+// literalOf returns value as a literal, if it can be written as one.
+func literalOf(value any) (literal, bool) {
+	switch v := value.(type) {
+	case nil:
+		null := true
+		return literal{null: &null}, true
+	case string:
+		return literal{string: &v}, true
+	case bool:
+		return literal{bool: &v}, true
+	case int:
+		return intToLiteral(int64(v)), true
+	case int8:
+		return intToLiteral(int64(v)), true
+	case int16:
+		return intToLiteral(int64(v)), true
+	case int32:
+		return intToLiteral(int64(v)), true
+	case int64:
+		return intToLiteral(v), true
+	case uint:
+		return bigIntToLiteral(new(big.Int).SetUint64(uint64(v))), true
+	case uint8:
+		return intToLiteral(int64(v)), true
+	case uint16:
+		return intToLiteral(int64(v)), true
+	case uint32:
+		return intToLiteral(int64(v)), true
+	case uint64:
+		return bigIntToLiteral(new(big.Int).SetUint64(v)), true
+	case *big.Int:
+		return bigIntToLiteral(v), true
+	case float32:
+		return literalOf(float64(v))
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return literal{}, false
+		}
+		if v == 0 {
+			v = 0 // -0 has no literal
+		}
+		return literal{float64: &v}, true
+	}
+	return literal{}, false
+}
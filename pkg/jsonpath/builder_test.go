@@ -0,0 +1,158 @@
+package jsonpath
+
+import (
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	two, minusOne := 2, -1
+	tests := []struct {
+		name     string
+		builder  *Builder
+		expected string
+	}{
+		{
+			name:     "Example",
+			builder:  Root().Child("paths").Name("/pets/{id}").Wildcard().Filter(Rel("x-internal").Exists()),
+			expected: "$.paths['/pets/{id}'].*[?@['x-internal']]",
+		},
+		{
+			name:     "Shorthand names",
+			builder:  Root().Child("_a1").Child("é").Child("1a").Child("").Child("a b").Child("true").Child("length"),
+			expected: "$._a1.é['1a']['']['a b']['true']['length']",
+		},
+		{
+			name:     "Escaped names",
+			builder:  Root().Name("it's", `back\slash`, "new\nline", "tab\t", "\x01", "\"", "☃"),
+			expected: `$['it\'s', 'back\\slash', 'new\nline', 'tab\t', '\u0001', '"', '☃']`,
+		},
+		{
+			name:     "Indices and slices",
+			builder:  Root().Index(0, -1).Slice(nil, nil, nil).Slice(&two, nil, &minusOne),
+			expected: "$[0, -1][:][2::-1]",
+		},
+		{
+			name:     "Descendants",
+			builder:  Root().Descendants().Child("a").Descendants().Wildcard().Descendants().Name("b").Descendants().Index(1),
+			expected: "$..a..*..['b']..[1]",
+		},
+		{
+			name:     "Property names",
+			builder:  Root().Child("paths").Wildcard().PropertyName(),
+			expected: "$.paths.*~",
+		},
+		{
+			name: "Comparisons",
+			builder: Root().Child("items").Filter(And(
+				Rel("price").Lt(10.5),
+				Rel("name").Ne("it's"),
+				Rel("n").Ge(new(big.Int).Lsh(big.NewInt(1), 70)),
+				Rel("a", "b").Eq(Root().Child("c").Index(0)),
+				Rel("d").Le(nil),
+				Rel("e").Gt(uint64(1)),
+			)),
+			expected: "$.items[?@.price < 10.5 && @.name != 'it\\'s' && @.n >= 1180591620717411303424 && @.a.b == $.c[0] && @.d <= null && @.e > 1]",
+		},
+		{
+			name:     "Logical operators",
+			builder:  Root().Filter(Or(And(Rel("a").Exists(), Or(Rel("b").Exists(), Rel("c").Eq(true))), Not(Rel("d").Exists()))),
+			expected: "$[?@.a && (@.b || @.c == true) || !(@.d)]",
+		},
+		{
+			name: "Functions",
+			builder: Root().Filter(And(
+				Rel("tags").Length().Gt(2),
+				Rel().Descendants().Child("x").Count().Eq(Rel("n").Length()),
+				Rel("name").Match("[a-z]+"),
+				Not(Rel("name").Search("it's")),
+				Rel().Descendants().Child("y").Value().Eq("y"),
+			)),
+			expected: "$[?length(@.tags) > 2 && count(@..x) == length(@.n) && match(@.name, '[a-z]+') && !(search(@.name, 'it\\'s')) && value(@..y) == 'y']",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := test.builder.Build(config.WithPropertyNameExtension())
+			if path.String() != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, path)
+			}
+			reparsed, err := NewPath(path.String(), config.WithPropertyNameExtension())
+			if err != nil {
+				t.Fatalf("Error parsing the built query: %v", err)
+			}
+			if reparsed.String() != path.String() {
+				t.Errorf("Expected %s to round-trip, got %s", path, reparsed)
+			}
+		})
+	}
+}
+
+func TestBuilderNames(t *testing.T) {
+	names := []string{"it's", "a\"b", "new\nline", "\r\t\b\f", "\x00\x1f\x7f", `A`, "☃ \U0001F600", "\u2028", "$.a[0]", "@", "a b"}
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, name := range names {
+		root.Content = append(root.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "value"})
+	}
+
+	// filters select from the members of the root, so they are evaluated against [root]
+	wrapped := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{root}}
+
+	for _, name := range names {
+		for _, path := range []*JSONPath{Root().Child(name).Build(), Root().Name(name).Build(), Root().Filter(Rel(name).Eq("value")).Build()} {
+			reparsed, err := NewPath(path.String())
+			if err != nil {
+				t.Fatalf("Error parsing %s built for %q: %v", path, name, err)
+			}
+			if reparsed.String() != path.String() {
+				t.Errorf("Expected %s to round-trip, got %s", path, reparsed)
+			}
+			document := root
+			if strings.Contains(path.String(), "?") {
+				document = wrapped
+			}
+			if result := reparsed.Query(document); len(result) != 1 {
+				t.Errorf("Expected %s to select the member %q, got %d nodes", path, name, len(result))
+			}
+		}
+	}
+}
+
+func TestBuilderImmutable(t *testing.T) {
+	base := Root().Child("paths")
+	a, b := base.Child("a"), base.Child("b")
+	if base.String() != "$.paths" || a.String() != "$.paths.a" || b.String() != "$.paths.b" {
+		t.Errorf("Expected builders to be independent, got %s, %s and %s", base, a, b)
+	}
+}
+
+func TestBuilderPanics(t *testing.T) {
+	tests := []struct {
+		name        string
+		build       func()
+		errorSubstr string
+	}{
+		{name: "Relative query", build: func() { Rel("a").Build() }, errorSubstr: "cannot build the relative query @.a"},
+		{name: "Non-singular comparison", build: func() { Rel().Wildcard().Eq(1) }, errorSubstr: "@.* is not a singular query"},
+		{name: "Non-singular operand", build: func() { Rel("a").Eq(Root().Descendants().Child("b")) }, errorSubstr: "$..b is not a singular query"},
+		{name: "Non-singular length", build: func() { Rel("a").Name("b", "c").Length() }, errorSubstr: "is not a singular query"},
+		{name: "Unsupported value", build: func() { Rel("a").Eq([]int{1}) }, errorSubstr: "cannot compare with [1]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if message, _ := r.(string); !strings.Contains(message, test.errorSubstr) {
+					t.Errorf("Expected a panic containing %q, got %v", test.errorSubstr, r)
+				}
+			}()
+			test.build()
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package config
 
+import "gopkg.in/yaml.v3"
+
 type Option func(*config)
 
 // WithPropertyNameExtension enables the use of the "~" character to access a property key.
@@ -10,20 +12,89 @@ func WithPropertyNameExtension() Option {
 	}
 }
 
+// WithAliasResolution makes queries against *yaml.Node trees see through YAML aliases:
+// an alias (*name) is treated as the node its anchor (&name) refers to, and merge keys
+// (<<: *base) are expanded in the YAML 1.1 way, with the mapping's own keys taking
+// precedence over merged ones, and earlier merged mappings over later ones. Nodes that
+// contain themselves through an alias are not descended into again.
+func WithAliasResolution() Option {
+	return func(cfg *config) {
+		cfg.aliasResolution = true
+	}
+}
+
+// DefaultMaxAliasExpansions is the number of aliases a single evaluation may resolve when
+// WithAliasResolution is enabled, unless set by WithMaxAliasExpansions. It is far more than
+// evaluating a query against a large hand-written document takes, but stops one that
+// expands exponentially in well under a second.
+const DefaultMaxAliasExpansions = 100000
+
+// WithMaxAliasExpansions limits how many aliases a single evaluation may resolve, so that
+// documents that expand exponentially (a "billion laughs") can't exhaust resources. An
+// alias is counted each time evaluation reads the node it refers to, to select it, descend
+// into it or compare it; looking up the members of a merge key is not counted. A limit of
+// 0 or less means no limit.
+//
+// Once the limit is reached, evaluation stops: QueryContext returns a *LimitError, while
+// Query and the other methods that don't return an error return the nodes found so far.
+func WithMaxAliasExpansions(n int) Option {
+	return func(cfg *config) {
+		cfg.maxAliasExpansions = n
+	}
+}
+
+// TagResolver maps a YAML scalar to the value filters compare it as, and that Node.Value
+// returns for it: nil, a bool, a string, a number or a time.Time, which compares
+// chronologically with other times and with strings holding a timestamp. It returns false
+// to leave the scalar as it is.
+type TagResolver func(node *yaml.Node) (any, bool)
+
+// WithTagResolver sets how scalars with the given tag, such as "!!timestamp" or "!Ref",
+// are resolved when queried as *yaml.Node trees. Without a resolver, a scalar with a tag
+// other than !!str, !!int, !!float, !!bool or !!null only equals scalars with the same tag
+// and value. ResolveTimestamp is set for !!timestamp unless overridden; a nil resolver
+// unsets it.
+func WithTagResolver(tag string, resolver TagResolver) Option {
+	return func(cfg *config) {
+		cfg.tagResolvers[tag] = resolver
+	}
+}
+
 type Config interface {
 	PropertyNameEnabled() bool
+	AliasResolutionEnabled() bool
+	MaxAliasExpansions() int
+	TagResolver(tag string) TagResolver
 }
 
 type config struct {
 	propertyNameExtension bool
+	aliasResolution       bool
+	maxAliasExpansions    int
+	tagResolvers          map[string]TagResolver
 }
 
 func (c *config) PropertyNameEnabled() bool {
 	return c.propertyNameExtension
 }
 
+func (c *config) AliasResolutionEnabled() bool {
+	return c.aliasResolution
+}
+
+func (c *config) MaxAliasExpansions() int {
+	return c.maxAliasExpansions
+}
+
+func (c *config) TagResolver(tag string) TagResolver {
+	return c.tagResolvers[tag]
+}
+
 func New(opts ...Option) Config {
-	cfg := &config{}
+	cfg := &config{
+		maxAliasExpansions: DefaultMaxAliasExpansions,
+		tagResolvers:       map[string]TagResolver{"!!timestamp": ResolveTimestamp},
+	}
 	for _, opt := range opts {
 		opt(cfg)
 	}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+	"time"
+)
+
+// timestampFormats are the forms of !!timestamp yaml.v3 decodes to a time.Time.
+var timestampFormats = []string{
+	"2006-1-2T15:4:5.999999999Z07:00",
+	"2006-1-2t15:4:5.999999999Z07:00",
+	"2006-1-2 15:4:5.999999999",
+	"2006-1-2",
+}
+
+// ResolveTimestamp is the TagResolver for !!timestamp scalars, such as 2024-01-02 or
+// 2024-01-02T15:04:05Z. It resolves them to a time.Time, so that they compare
+// chronologically.
+func ResolveTimestamp(node *yaml.Node) (any, bool) {
+	t, ok := ParseTimestamp(node.Value)
+	return t, ok
+}
+
+// ParseTimestamp parses a timestamp in any of the forms yaml.v3 accepts. Times without a
+// time zone are in UTC.
+func ParseTimestamp(value string) (time.Time, bool) {
+	// the year is always four digits, which rules out most other strings quickly
+	i := 0
+	for ; i < len(value) && value[i] >= '0' && value[i] <= '9'; i++ {
+	}
+	if i != 4 || i == len(value) || value[i] != '-' {
+		return time.Time{}, false
+	}
+	for _, format := range timestampFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,81 @@
+package jsonpath
+
+import (
+	"errors"
+	"gopkg.in/yaml.v3"
+	"io"
+)
+
+// DocumentMatch is a node selected from one document of a multi-document YAML stream.
+type DocumentMatch struct {
+	Match
+	// Document is the index of the document within the stream the node belongs to. It is -1
+	// for the array of documents itself, as selected by $ with WithDocumentArray.
+	Document int
+}
+
+// WithDocumentArray makes QueryDocuments evaluate the query once, against a virtual array
+// holding each document of the stream, instead of against each document in turn. $[0] is
+// then the first document, and e.g. $[?@.kind == 'Service'] selects whole documents.
+func WithDocumentArray() QueryOption {
+	return func(o *queryOptions) {
+		o.documentArray = true
+	}
+}
+
+// QueryDocuments decodes each document of the YAML stream read from r, such as a set of
+// Kubernetes manifests separated by ---, and evaluates the query against it, with $ bound
+// to that document. Matches are returned in order of their documents. The limits set by
+// opts apply to the stream as a whole.
+func (p *JSONPath) QueryDocuments(r io.Reader, opts ...QueryOption) ([]DocumentMatch, error) {
+	ev := p.newYAMLEvaluation(nil)
+	for _, opt := range opts {
+		opt(&ev.options)
+	}
+
+	result := []DocumentMatch{}
+	collect := func(document int) error {
+		p.ast.each(ev, func(loc *location) bool {
+			if !ev.result() {
+				return false
+			}
+			match := DocumentMatch{Match: loc.match(), Document: document}
+			if ev.options.documentArray {
+				match.Document = -1
+				if len(match.Path) > 0 {
+					match.Document = match.Path[0].Index
+				}
+			}
+			result = append(result, match)
+			return true
+		})
+		return ev.err
+	}
+
+	array := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	decoder := yaml.NewDecoder(r)
+	for i := 0; ; i++ {
+		document := &yaml.Node{}
+		if err := decoder.Decode(document); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		ev.root = ev.yamlRoot(document)
+		if ev.options.documentArray {
+			array.Content = append(array.Content, yamlNodeOf(ev.root.node))
+			continue
+		}
+		if err := collect(i); err != nil {
+			return nil, err
+		}
+	}
+	if ev.options.documentArray {
+		ev.root = ev.yamlRoot(array)
+		if err := collect(-1); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,124 @@
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQueryDocuments(t *testing.T) {
+	const manifests = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+---
+---
+kind: Service
+metadata:
+  name: db
+`
+
+	tests := []struct {
+		name     string
+		input    string
+		opts     []QueryOption
+		expected []string
+	}{
+		{
+			name:     "Each document",
+			input:    "$.metadata.name",
+			expected: []string{"0 $['metadata']['name'] web", "1 $['metadata']['name'] web", "3 $['metadata']['name'] db"},
+		},
+		{
+			name:     "Root of each document",
+			input:    "$",
+			expected: []string{"0 $ kind: Service", "1 $ kind: Deployment", "2 $ null", "3 $ kind: Service"},
+		},
+		{
+			name:     "Filter within each document",
+			input:    "$[?@.replicas > 1].replicas",
+			expected: []string{"1 $['spec']['replicas'] 2"},
+		},
+		{
+			name:     "Document array",
+			input:    "$[?@.kind == 'Service'].metadata.name",
+			opts:     []QueryOption{WithDocumentArray()},
+			expected: []string{"0 $[0]['metadata']['name'] web", "3 $[3]['metadata']['name'] db"},
+		},
+		{
+			name:     "Document array by index",
+			input:    "$[-1].kind",
+			opts:     []QueryOption{WithDocumentArray()},
+			expected: []string{"3 $[3]['kind'] Service"},
+		},
+		{
+			name:     "Document array itself",
+			input:    "$",
+			opts:     []QueryOption{WithDocumentArray()},
+			expected: []string{"-1 $ 4 documents"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := NewPath(test.input)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			matches, err := path.QueryDocuments(strings.NewReader(manifests), test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var actual []string
+			for _, match := range matches {
+				actual = append(actual, fmt.Sprintf("%d %s %s", match.Document, match.Path, describeDocumentNode(match.Node)))
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("Expected:\n%v\nGot:\n%v", test.expected, actual)
+			}
+		})
+	}
+}
+
+// describeDocumentNode summarizes a node of the manifests above by its kind.
+func describeDocumentNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		if kind, ok := NewYAMLNode(node).Member("kind"); ok {
+			return "kind: " + kind.Value().(string)
+		}
+	case yaml.SequenceNode:
+		return fmt.Sprintf("%d documents", len(node.Content))
+	}
+	if node.ShortTag() == "!!null" {
+		return "null"
+	}
+	return node.Value
+}
+
+func TestQueryDocumentsLimits(t *testing.T) {
+	path, err := NewPath("$.a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = path.QueryDocuments(strings.NewReader("a: 1\n---\na: 2\n---\na: 3\n"), WithMaxResults(2))
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != LimitResults {
+		t.Errorf("Expected a results limit error across documents, got %v", err)
+	}
+
+	_, err = path.QueryDocuments(strings.NewReader("a: 1\n---\na: [\n"))
+	if err == nil {
+		t.Error("Expected a decoding error")
+	}
+}
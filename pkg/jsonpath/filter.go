@@ -1,9 +1,11 @@
 package jsonpath
 
 import (
-	"gopkg.in/yaml.v3"
+	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // filter-selector     = "?" S logical-expr
@@ -103,14 +105,14 @@ type resolvedArgument struct {
 	nodes   []*literal
 }
 
-func (a functionArgument) Eval(idx index, node *yaml.Node, root *yaml.Node) resolvedArgument {
+func (a functionArgument) Eval(node *location, ev *evaluation) resolvedArgument {
 	if a.literal != nil {
 		return resolvedArgument{kind: functionArgTypeLiteral, literal: a.literal}
 	} else if a.filterQuery != nil {
-		result := a.filterQuery.Query(idx, node, root)
+		result := a.filterQuery.Query(node, ev)
 		lits := make([]*literal, len(result))
-		for i, node := range result {
-			lit := nodeToLiteral(node)
+		for i, loc := range result {
+			lit := nodeToLiteral(loc.node)
 			lits[i] = &lit
 		}
 		if len(result) != 1 {
@@ -119,10 +121,10 @@ func (a functionArgument) Eval(idx index, node *yaml.Node, root *yaml.Node) reso
 			return resolvedArgument{kind: functionArgTypeLiteral, literal: lits[0]}
 		}
 	} else if a.logicalExpr != nil {
-		res := a.logicalExpr.Matches(idx, node, root)
+		res := a.logicalExpr.Matches(node, ev)
 		return resolvedArgument{kind: functionArgTypeLiteral, literal: &literal{bool: &res}}
 	} else if a.functionExpr != nil {
-		res := a.functionExpr.Evaluate(idx, node, root)
+		res := a.functionExpr.Evaluate(node, ev)
 		return resolvedArgument{kind: functionArgTypeLiteral, literal: &res}
 	}
 	return resolvedArgument{}
@@ -248,10 +250,12 @@ type literal struct {
 	// we generally decompose these into their component parts for easier evaluation
 	integer *int
 	float64 *float64
+	bigInt  *big.Int // integers beyond ±MaxSafeFloat, which a float64 can't hold exactly
 	string  *string
 	bool    *bool
 	null    *bool
-	node    *yaml.Node
+	time    *time.Time // a timestamp, as resolved by config.ResolveTimestamp
+	node    Node
 }
 
 func (l literal) ToString() string {
@@ -259,10 +263,12 @@ func (l literal) ToString() string {
 		return strconv.Itoa(*l.integer)
 	} else if l.float64 != nil {
 		return strconv.FormatFloat(*l.float64, 'f', -1, 64)
+	} else if l.bigInt != nil {
+		return l.bigInt.String()
 	} else if l.string != nil {
 		builder := strings.Builder{}
 		builder.WriteString("'")
-		builder.WriteString(escapeString(*l.string))
+		builder.WriteString(escapeQuoted(*l.string, '\''))
 		builder.WriteString("'")
 		return builder.String()
 	} else if l.bool != nil {
@@ -277,53 +283,43 @@ func (l literal) ToString() string {
 		} else {
 			return "null"
 		}
+	} else if l.time != nil {
+		return l.time.Format(time.RFC3339Nano)
 	} else if l.node != nil {
-		switch l.node.Kind {
-		case yaml.ScalarNode:
-			return l.node.Value
-		case yaml.SequenceNode:
+		switch l.node.Kind() {
+		case NodeArray:
 			builder := strings.Builder{}
 			builder.WriteString("[")
-			for i, child := range l.node.Content {
+			for i := range l.node.Len() {
 				if i > 0 {
 					builder.WriteString(",")
 				}
-				builder.WriteString(literal{node: child}.ToString())
+				builder.WriteString(nodeToLiteral(l.node.Index(i)).ToString())
 			}
 			builder.WriteString("]")
 			return builder.String()
-		case yaml.MappingNode:
+		case NodeObject:
 			builder := strings.Builder{}
 			builder.WriteString("{")
-			for i, child := range l.node.Content {
+			i := 0
+			for name, child := range l.node.Members() {
 				if i > 0 {
 					builder.WriteString(",")
 				}
-				builder.WriteString(literal{node: child}.ToString())
+				builder.WriteString(literal{string: &name}.ToString())
+				builder.WriteString(":")
+				builder.WriteString(nodeToLiteral(child).ToString())
+				i++
 			}
 			builder.WriteString("}")
 			return builder.String()
+		default:
+			return fmt.Sprint(l.node.Value())
 		}
 	}
 	return ""
 }
 
-func escapeString(value string) string {
-	b := strings.Builder{}
-	for i := 0; i < len(value); i++ {
-		if value[i] == '\n' {
-			b.WriteString("\\\\n")
-		} else if value[i] == '\\' {
-			b.WriteString("\\\\")
-		} else if value[i] == '\'' {
-			b.WriteString("\\'")
-		} else {
-			b.WriteByte(value[i])
-		}
-	}
-	return b.String()
-}
-
 type absQuery jsonPathAST
 
 func (q absQuery) ToString() string {
@@ -0,0 +1,285 @@
+package jsonpath
+
+import (
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/ast"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// FormatOption configures how Format writes a query.
+type FormatOption func(*formatOptions)
+
+type formatOptions struct {
+	notation      Notation
+	quote         byte
+	compact       bool
+	normalizePath bool
+}
+
+// Notation is how Format writes segments selecting a single member name, or every member.
+type Notation int
+
+const (
+	NotationAsWritten Notation = iota // as in the query: .name or ['name'], .* or [*]
+	NotationDot                       // .name and .* wherever they are legal
+	NotationBracket                   // ['name'] and [*] everywhere
+)
+
+// QuoteStyle is the quote Format writes string literals and name selectors in.
+type QuoteStyle int
+
+const (
+	QuoteSingle QuoteStyle = iota // 'name'
+	QuoteDouble                   // "name"
+)
+
+// WithNotation sets how segments selecting a single name, or every member, are written.
+// With NotationDot, ['name'] is written .name only if the name can be read back as such.
+func WithNotation(notation Notation) FormatOption {
+	return func(o *formatOptions) {
+		o.notation = notation
+	}
+}
+
+// WithQuoteStyle sets the quote string literals and name selectors are written in.
+func WithQuoteStyle(style QuoteStyle) FormatOption {
+	return func(o *formatOptions) {
+		o.quote = '\''
+		if style == QuoteDouble {
+			o.quote = '"'
+		}
+	}
+}
+
+// WithOperatorSpacing sets whether the comparison and logical operators of filters are
+// surrounded by spaces, as in @.a == 1 && @.b, which is the default, or not, as in
+// @.a==1&&@.b.
+func WithOperatorSpacing(spaced bool) FormatOption {
+	return func(o *formatOptions) {
+		o.compact = !spaced
+	}
+}
+
+// WithNormalizedPaths makes a query that is a Normalized Path, selecting a single node by
+// member names and non-negative indices only, be written in its canonical form, such as
+// $['paths']['/pets'][0], whatever the other options. Two queries for the same path are
+// then written the same.
+func WithNormalizedPaths() FormatOption {
+	return func(o *formatOptions) {
+		o.normalizePath = true
+	}
+}
+
+// Format returns the query as text, written as opts set. Without options, it is the same as
+// String. The query it returns parses to the same query, whatever the options.
+func (p *JSONPath) Format(opts ...FormatOption) string {
+	f := formatter{formatOptions: formatOptions{quote: '\''}}
+	for _, opt := range opts {
+		opt(&f.formatOptions)
+	}
+	query := p.AST()
+	if f.normalizePath {
+		if path, ok := normalizedPathOf(query); ok {
+			return path.String()
+		}
+	}
+	f.query(query)
+	return f.String()
+}
+
+// normalizedPathOf returns the Normalized Path query stands for, if it is one.
+func normalizedPathOf(query *ast.Query) (NormalizedPath, bool) {
+	path := NormalizedPath{}
+	for _, s := range query.Segments {
+		if s.Kind != ast.ChildSegment || len(s.Selectors) != 1 {
+			return nil, false
+		}
+		switch selector := s.Selectors[0].(type) {
+		case *ast.NameSelector:
+			path = append(path, NameElement(selector.Name))
+		case *ast.IndexSelector:
+			if selector.Index < 0 || int64(int(selector.Index)) != selector.Index {
+				return nil, false
+			}
+			path = append(path, IndexElement(int(selector.Index)))
+		default:
+			return nil, false
+		}
+	}
+	return path, true
+}
+
+// formatter writes a syntax tree as Format's options set.
+type formatter struct {
+	formatOptions
+	strings.Builder
+}
+
+func (f *formatter) query(q *ast.Query) {
+	if q.Relative {
+		f.WriteString("@")
+	} else {
+		f.WriteString("$")
+	}
+	for _, s := range q.Segments {
+		f.segment(s)
+	}
+}
+
+func (f *formatter) segment(s *ast.Segment) {
+	switch s.Kind {
+	case ast.PropertyNameSegment:
+		f.WriteString("~")
+		return
+	case ast.DescendantSegment:
+		f.WriteString("..")
+	}
+	if f.dotted(s) {
+		if s.Kind == ast.ChildSegment {
+			f.WriteString(".")
+		}
+		if name, ok := s.Selectors[0].(*ast.NameSelector); ok {
+			f.WriteString(name.Name)
+		} else {
+			f.WriteString("*")
+		}
+		return
+	}
+	f.WriteString("[")
+	for i, selector := range s.Selectors {
+		if i > 0 {
+			f.WriteString(", ")
+		}
+		f.selector(selector)
+	}
+	f.WriteString("]")
+}
+
+// dotted reports whether s is to be written in dot notation.
+func (f *formatter) dotted(s *ast.Segment) bool {
+	switch f.notation {
+	case NotationBracket:
+		return false
+	case NotationDot:
+		if len(s.Selectors) != 1 {
+			return false
+		}
+		switch selector := s.Selectors[0].(type) {
+		case *ast.NameSelector:
+			return isShorthandName(selector.Name)
+		case *ast.WildcardSelector:
+			return true
+		}
+		return false
+	}
+	return s.Dot
+}
+
+func (f *formatter) selector(s ast.Selector) {
+	switch s := s.(type) {
+	case *ast.NameSelector:
+		f.string(s.Name)
+	case *ast.WildcardSelector:
+		f.WriteString("*")
+	case *ast.IndexSelector:
+		f.WriteString(strconv.FormatInt(s.Index, 10))
+	case *ast.SliceSelector:
+		if s.Start != nil {
+			f.WriteString(strconv.FormatInt(*s.Start, 10))
+		}
+		f.WriteString(":")
+		if s.End != nil {
+			f.WriteString(strconv.FormatInt(*s.End, 10))
+		}
+		if s.Step != nil {
+			f.WriteString(":")
+			f.WriteString(strconv.FormatInt(*s.Step, 10))
+		}
+	case *ast.FilterSelector:
+		f.WriteString("?")
+		f.expr(s.Expr)
+	}
+}
+
+func (f *formatter) string(value string) {
+	f.WriteByte(f.quote)
+	f.WriteString(escapeQuoted(value, f.quote))
+	f.WriteByte(f.quote)
+}
+
+func (f *formatter) operator(op string) {
+	if f.compact {
+		f.WriteString(op)
+	} else {
+		f.WriteString(" " + op + " ")
+	}
+}
+
+// expr writes any node of a filter expression.
+func (f *formatter) expr(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.OrExpr:
+		for i, operand := range n.Operands {
+			if i > 0 {
+				f.operator("||")
+			}
+			f.expr(operand)
+		}
+	case *ast.AndExpr:
+		for i, operand := range n.Operands {
+			if i > 0 {
+				f.operator("&&")
+			}
+			f.expr(operand)
+		}
+	case *ast.ParenExpr:
+		if n.Not {
+			f.WriteString("!")
+		}
+		f.WriteString("(")
+		f.expr(n.Expr)
+		f.WriteString(")")
+	case *ast.ComparisonExpr:
+		f.expr(n.Left)
+		f.operator(string(n.Op))
+		f.expr(n.Right)
+	case *ast.TestExpr:
+		if n.Not {
+			f.WriteString("!")
+		}
+		f.expr(n.Operand)
+	case *ast.FunctionCall:
+		f.WriteString(n.Name)
+		f.WriteString("(")
+		for i, arg := range n.Args {
+			if i > 0 {
+				f.WriteString(", ")
+			}
+			f.expr(arg)
+		}
+		f.WriteString(")")
+	case *ast.Query:
+		f.query(n)
+	case *ast.Literal:
+		f.literal(n.Value)
+	}
+}
+
+// literal writes a literal as literal.ToString does.
+func (f *formatter) literal(value any) {
+	switch v := value.(type) {
+	case nil:
+		f.WriteString("null")
+	case string:
+		f.string(v)
+	case bool:
+		f.WriteString(strconv.FormatBool(v))
+	case int64:
+		f.WriteString(strconv.FormatInt(v, 10))
+	case *big.Int:
+		f.WriteString(v.String())
+	case float64:
+		f.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+}
@@ -0,0 +1,125 @@
+package jsonpath
+
+import (
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     []FormatOption
+		expected string
+	}{
+		{
+			name:     "No options",
+			input:    `$.paths["/pets"].*[?@.price<10&&!(@["x-internal"]||@.a=="it's")]..b[0:2]~`,
+			expected: `$.paths['/pets'].*[?@.price < 10 && !(@['x-internal'] || @.a == 'it\'s')]..b[0:2]~`,
+		},
+		{
+			name:     "Dot notation",
+			input:    `$['paths']['pets', 'dogs'][*]..['a']..[*]['x-internal']['true']['1a'][?@['n'] == $['m']]`,
+			opts:     []FormatOption{WithNotation(NotationDot)},
+			expected: `$.paths['pets', 'dogs'].*..a..*['x-internal']['true']['1a'][?@.n == $.m]`,
+		},
+		{
+			name:     "Bracket notation",
+			input:    `$.paths.*..a..*[?@.n == $.m[0]]~`,
+			opts:     []FormatOption{WithNotation(NotationBracket)},
+			expected: `$['paths'][*]..['a']..[*][?@['n'] == $['m'][0]]~`,
+		},
+		{
+			name:     "Double quotes",
+			input:    `$['it\'s', 'say "hi"'][?@.a == 'b' && match(@.c, '\\d+')]`,
+			opts:     []FormatOption{WithQuoteStyle(QuoteDouble)},
+			expected: `$["it's", "say \"hi\""][?@.a == "b" && match(@.c, "\\d+")]`,
+		},
+		{
+			name:     "Single quotes",
+			input:    `$["it's", "a\nb"]`,
+			opts:     []FormatOption{WithQuoteStyle(QuoteSingle)},
+			expected: `$['it\'s', 'a\nb']`,
+		},
+		{
+			name:     "Without operator spacing",
+			input:    `$[?@.a == 1 && (@.b != 'x' || length(@.c) >= 2)]`,
+			opts:     []FormatOption{WithOperatorSpacing(false)},
+			expected: `$[?@.a==1&&(@.b!='x'||length(@.c)>=2)]`,
+		},
+		{
+			name:     "With operator spacing",
+			input:    `$[?@.a==1||@.b<=-2.5]`,
+			opts:     []FormatOption{WithOperatorSpacing(true)},
+			expected: `$[?@.a == 1 || @.b <= -2.5]`,
+		},
+		{
+			name:     "Normalized path",
+			input:    `$.paths["/pets"].get.parameters[0]`,
+			opts:     []FormatOption{WithNormalizedPaths(), WithNotation(NotationDot), WithQuoteStyle(QuoteDouble)},
+			expected: `$['paths']['/pets']['get']['parameters'][0]`,
+		},
+		{
+			name:     "Normalized path escapes",
+			input:    `$["a\u0001\"b"]`,
+			opts:     []FormatOption{WithNormalizedPaths()},
+			expected: `$['a\u0001"b']`,
+		},
+		{
+			name:     "Not a normalized path",
+			input:    `$.paths["/pets"][-1]`,
+			opts:     []FormatOption{WithNormalizedPaths(), WithQuoteStyle(QuoteDouble)},
+			expected: `$.paths["/pets"][-1]`,
+		},
+		{
+			name:     "Root as a normalized path",
+			input:    `$`,
+			opts:     []FormatOption{WithNormalizedPaths()},
+			expected: `$`,
+		},
+		{
+			name:     "Literals",
+			input:    `$[?@.a == null || @.b == true || @.c == 123456789012345678901234567890 || @.d == 0.5]`,
+			opts:     []FormatOption{WithOperatorSpacing(false)},
+			expected: `$[?@.a==null||@.b==true||@.c==123456789012345678901234567890||@.d==0.5]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := NewPath(test.input, config.WithPropertyNameExtension())
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			actual := path.Format(test.opts...)
+			if actual != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, actual)
+			}
+			reparsed, err := NewPath(actual, config.WithPropertyNameExtension())
+			if err != nil {
+				t.Fatalf("Error parsing the formatted query: %v", err)
+			}
+			canonical := WithNotation(NotationBracket)
+			if reparsed.Format(canonical) != path.Format(canonical) {
+				t.Errorf("Expected %s to parse to the same query as %s", actual, test.input)
+			}
+		})
+	}
+}
+
+func TestFormatMatchesString(t *testing.T) {
+	for _, input := range []string{
+		"$",
+		"$.a['b', 1][1:2:3][::-1]..*..c..['d'][*]",
+		"$[?@.a && (@.b || !(@.c)) && count(@..d) > 0 && value($.e) != 'f']",
+		`$['it\'s'][?search(@.a, 'b\\.c')]`,
+	} {
+		path, err := NewPath(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path.Format() != path.String() {
+			t.Errorf("Expected Format() to be %s, got %s", path.String(), path.Format())
+		}
+	}
+}
@@ -1,10 +1,12 @@
 package jsonpath
 
 import (
-	"fmt"
+	"errors"
 	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
 	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/token"
 	"gopkg.in/yaml.v3"
+	"iter"
+	"reflect"
 )
 
 func NewPath(input string, opts ...config.Option) (*JSONPath, error) {
@@ -12,7 +14,7 @@ func NewPath(input string, opts ...config.Option) (*JSONPath, error) {
 	tokens := tokenizer.Tokenize()
 	for i := 0; i < len(tokens); i++ {
 		if tokens[i].Token == token.ILLEGAL {
-			return nil, fmt.Errorf(tokenizer.ErrorString(&tokens[i], "unexpected token"))
+			return nil, errors.New(tokenizer.ErrorString(&tokens[i], "unexpected token"))
 		}
 	}
 	parser := newParserPrivate(tokenizer, tokens, opts...)
@@ -23,8 +25,105 @@ func NewPath(input string, opts ...config.Option) (*JSONPath, error) {
 	return parser, nil
 }
 
+// Query returns the nodes the query selects within root. If evaluation stops early, as it
+// does when config.WithMaxAliasExpansions is exceeded, it returns the nodes found so far;
+// use QueryContext to find out why it stopped.
 func (p *JSONPath) Query(root *yaml.Node) []*yaml.Node {
-	return p.ast.Query(root, root)
+	return nodes(p.ast.query(p.newYAMLEvaluation(root)))
+}
+
+// QueryWithPaths is like Query, but returns each match with its Normalized Path, its parent
+// node and the member name or array index it was found under.
+func (p *JSONPath) QueryWithPaths(root *yaml.Node) NodeList {
+	return toNodeList(p.ast.query(p.newYAMLEvaluation(root)))
+}
+
+// QueryNode evaluates the query against any document model implementing Node.
+func (p *JSONPath) QueryNode(root Node) []Node {
+	locations := p.ast.query(newEvaluation(&location{node: root}))
+	result := make([]Node, len(locations))
+	for i, loc := range locations {
+		result[i] = loc.node
+	}
+	return result
+}
+
+// AllNodes is like QueryNode, but yields each node together with its Normalized Path as it
+// is found.
+func (p *JSONPath) AllNodes(root Node) iter.Seq2[NormalizedPath, Node] {
+	return func(yield func(NormalizedPath, Node) bool) {
+		p.ast.each(newEvaluation(&location{node: root}), func(loc *location) bool {
+			return yield(loc.path(), loc.node)
+		})
+	}
+}
+
+// QueryValue evaluates the query against a plain Go value, such as the result of
+// unmarshalling JSON or YAML into an any or a struct, and returns the selected values. See
+// NewValueNode for how values are interpreted. Member names selected with "~" are returned
+// as strings.
+func (p *JSONPath) QueryValue(root any) []any {
+	nodes := p.QueryNode(NewValueNode(root))
+	result := make([]any, len(nodes))
+	for i, node := range nodes {
+		result[i] = node.Value()
+	}
+	return result
+}
+
+// QueryReflect is like QueryValue, but returns each selected value as a reflect.Value. When
+// root is a pointer, values reached through it are addressable, and so can be set.
+func (p *JSONPath) QueryReflect(root any) []reflect.Value {
+	nodes := p.QueryNode(NewValueNode(root))
+	result := make([]reflect.Value, len(nodes))
+	for i, node := range nodes {
+		if r, ok := node.(reflectNode); ok {
+			result[i] = r.v
+		} else {
+			result[i] = reflect.ValueOf(node.Value())
+		}
+	}
+	return result
+}
+
+// All returns an iterator over the nodes selected by the query, in the same order as Query.
+// Nodes are produced as they are found, so breaking out of the loop stops evaluation.
+func (p *JSONPath) All(root *yaml.Node) iter.Seq[*yaml.Node] {
+	return func(yield func(*yaml.Node) bool) {
+		p.ast.each(p.newYAMLEvaluation(root), func(loc *location) bool {
+			return yield(yamlNodeOf(loc.node))
+		})
+	}
+}
+
+// AllWithPaths is like All, but yields each match with its location, as QueryWithPaths does.
+func (p *JSONPath) AllWithPaths(root *yaml.Node) iter.Seq[Match] {
+	return func(yield func(Match) bool) {
+		p.ast.each(p.newYAMLEvaluation(root), func(loc *location) bool {
+			return yield(loc.match())
+		})
+	}
+}
+
+// Exists reports whether the query selects any node. Evaluation stops at the first match.
+func (p *JSONPath) Exists(root *yaml.Node) bool {
+	ev := p.newYAMLEvaluation(root)
+	return exists(p.ast.segments, ev.root, ev)
+}
+
+// First returns the first node selected by the query, or nil if there is none. Evaluation
+// stops as soon as it is found.
+func (p *JSONPath) First(root *yaml.Node) *yaml.Node {
+	for node := range p.All(root) {
+		return node
+	}
+	return nil
+}
+
+// Count returns the number of nodes selected by the query, without collecting them.
+func (p *JSONPath) Count(root *yaml.Node) int {
+	ev := p.newYAMLEvaluation(root)
+	return count(p.ast.segments, ev.root, ev)
 }
 
 func (p *JSONPath) String() string {
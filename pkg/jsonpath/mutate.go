@@ -0,0 +1,545 @@
+package jsonpath
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"slices"
+)
+
+// Set replaces each node the query selects within root with value, and returns how many
+// nodes it replaced. Mapping values and sequence elements are replaced within their
+// parent, so that nothing else refers to the replaced node; mapping keys selected with the
+// "~" extension are replaced by value as the key, which must then be a scalar. If the root
+// node itself is selected, it is overwritten with value, unless it is a document node.
+//
+// The first node is replaced by value itself, and any others by copies of it, so that no
+// two places in the tree share a node. Nothing is changed if an error is returned.
+func (p *JSONPath) Set(root *yaml.Node, value *yaml.Node) (int, error) {
+	if value == nil {
+		return 0, errors.New("jsonpath: cannot set a nil node")
+	}
+	slots, err := p.slots(root)
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range slots {
+		if err := s.check(value); err != nil {
+			return 0, err
+		}
+	}
+	for i, s := range slots {
+		if i > 0 {
+			value = copyNode(value)
+		}
+		s.set(root, value)
+	}
+	return len(slots), nil
+}
+
+// Delete removes each node the query selects within root from its parent, and returns how
+// many nodes it removed. A mapping member is removed as a whole, key and value, whether
+// its value or its key (with the "~" extension) is selected; a sequence element is removed
+// from the sequence. When several elements of a sequence are selected, as by
+// $.tags[?@.deprecated], they are removed from last to first, so that the indices of the
+// others stay valid. The root node can't be removed, and neither can members a mapping
+// only has through a merge key. Nothing is changed if an error is returned.
+func (p *JSONPath) Delete(root *yaml.Node) (int, error) {
+	slots, err := p.slots(root)
+	if err != nil {
+		return 0, err
+	}
+	removals, err := removalsOf(slots)
+	if err != nil {
+		return 0, err
+	}
+	removeAll(removals)
+	return len(removals), nil
+}
+
+// removal is a run of entries of the Content of a mapping or sequence to remove.
+type removal struct {
+	parent *yaml.Node
+	start  int
+	n      int
+}
+
+// removalsOf returns how to remove the nodes in slots from their parents, once each.
+func removalsOf(slots []slot) ([]removal, error) {
+	seen := map[removal]bool{}
+	removals := make([]removal, 0, len(slots))
+	for _, s := range slots {
+		switch {
+		case s.parent == nil || s.parent.Kind == yaml.DocumentNode:
+			return nil, errors.New("jsonpath: cannot delete the root node")
+		case s.index < 0:
+			return nil, fmt.Errorf("jsonpath: cannot delete %s, which is merged from another mapping", s.path)
+		}
+		r := removal{parent: s.parent, start: s.index, n: 1}
+		if s.parent.Kind == yaml.MappingNode {
+			// the key and value of a member are removed together
+			r.start, r.n = s.index-s.index%2, 2
+		}
+		if !seen[r] {
+			seen[r] = true
+			removals = append(removals, r)
+		}
+	}
+	return removals, nil
+}
+
+// removeAll applies removals from the last entry of each parent to the first, so that
+// the indices of the others stay valid.
+func removeAll(removals []removal) {
+	slices.SortStableFunc(removals, func(a, b removal) int {
+		return cmp.Compare(b.start, a.start)
+	})
+	for _, r := range removals {
+		r.parent.Content = slices.Delete(r.parent.Content, r.start, r.start+r.n)
+	}
+}
+
+// Append appends value to each sequence the query selects within root, and returns how
+// many sequences it appended to. Value itself is inserted once, and copies of it anywhere
+// else. It returns an error, and changes nothing, if the query selects anything but
+// sequences.
+func (p *JSONPath) Append(root *yaml.Node, value *yaml.Node) (int, error) {
+	return p.insertInto(root, value, func(length int) (int, bool) {
+		return length, true
+	})
+}
+
+// Prepend is like Append, but inserts value at the start of each sequence.
+func (p *JSONPath) Prepend(root *yaml.Node, value *yaml.Node) (int, error) {
+	return p.insertInto(root, value, func(int) (int, bool) {
+		return 0, true
+	})
+}
+
+// Insert is like Append, but inserts value before the element at index in each sequence,
+// so that it takes its place. As with an index selector, a negative index counts back from
+// the end of the sequence, so that -1 inserts before the last element. An index equal to
+// the length of a sequence appends to it; any other index out of range is an error.
+func (p *JSONPath) Insert(root *yaml.Node, index int, value *yaml.Node) (int, error) {
+	return p.insertInto(root, value, func(length int) (int, bool) {
+		i := index
+		if i < 0 {
+			i += length
+		}
+		return i, i >= 0 && i <= length
+	})
+}
+
+// insertInto inserts value into each sequence the query selects, at the position returned
+// for its length.
+func (p *JSONPath) insertInto(root *yaml.Node, value *yaml.Node, position func(length int) (int, bool)) (int, error) {
+	if value == nil {
+		return 0, errors.New("jsonpath: cannot insert a nil node")
+	}
+	ev := p.newYAMLEvaluation(root)
+	locations := p.ast.query(ev)
+	if ev.err != nil {
+		return 0, ev.err
+	}
+	type insertion struct {
+		sequence *yaml.Node
+		index    int
+	}
+	seen := map[*yaml.Node]bool{}
+	insertions := make([]insertion, 0, len(locations))
+	for _, loc := range locations {
+		sequence := yamlNodeOf(loc.node)
+		if sequence.Kind != yaml.SequenceNode {
+			return 0, fmt.Errorf("jsonpath: cannot insert into %s, which is not an array (found %s)", loc.path(), loc.node.Kind())
+		}
+		if seen[sequence] {
+			continue
+		}
+		seen[sequence] = true
+		index, ok := position(len(sequence.Content))
+		if !ok {
+			return 0, fmt.Errorf("jsonpath: cannot insert into %s: the index is out of range for its %d elements", loc.path(), len(sequence.Content))
+		}
+		insertions = append(insertions, insertion{sequence, index})
+	}
+	for i, insertion := range insertions {
+		if i > 0 {
+			value = copyNode(value)
+		}
+		insertion.sequence.Content = slices.Insert(insertion.sequence.Content, insertion.index, value)
+	}
+	return len(insertions), nil
+}
+
+// InsertBefore inserts value before each sequence element the query selects within root,
+// and returns how many elements it inserted. When several elements of one sequence are
+// selected, the insertions are applied from the last element to the first, so that the
+// indices of the earlier ones stay valid. Value itself is inserted once, and copies of it anywhere else. It returns an
+// error, and changes nothing, if the query selects anything but sequence elements.
+func (p *JSONPath) InsertBefore(root *yaml.Node, value *yaml.Node) (int, error) {
+	return p.insertBeside(root, value, 0)
+}
+
+// InsertAfter is like InsertBefore, but inserts value after each selected element.
+func (p *JSONPath) InsertAfter(root *yaml.Node, value *yaml.Node) (int, error) {
+	return p.insertBeside(root, value, 1)
+}
+
+// insertBeside inserts value at offset from each sequence element the query selects.
+func (p *JSONPath) insertBeside(root *yaml.Node, value *yaml.Node, offset int) (int, error) {
+	if value == nil {
+		return 0, errors.New("jsonpath: cannot insert a nil node")
+	}
+	slots, err := p.slots(root)
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range slots {
+		if s.parent == nil || s.parent.Kind != yaml.SequenceNode {
+			return 0, fmt.Errorf("jsonpath: cannot insert beside %s, which is not an array element", s.path)
+		}
+	}
+	slices.SortStableFunc(slots, func(a, b slot) int {
+		return cmp.Compare(b.index, a.index)
+	})
+	for i, s := range slots {
+		if i > 0 {
+			value = copyNode(value)
+		}
+		s.parent.Content = slices.Insert(s.parent.Content, s.index+offset, value)
+	}
+	return len(slots), nil
+}
+
+// RenameKeys renames each mapping key a query ending in "~" selects within root, such as
+// $.components.schemas.*~, to what rename returns for it, and returns how many keys it
+// changed. It returns an error, and changes nothing, if a key would be renamed to the name
+// of another key of its mapping, as it is or after renaming.
+func (p *JSONPath) RenameKeys(root *yaml.Node, rename func(old string) string) (int, error) {
+	if len(p.ast.segments) == 0 || p.ast.segments[len(p.ast.segments)-1].kind != segmentKindProperyName {
+		return 0, fmt.Errorf("jsonpath: cannot rename keys selected by %s, which does not end in ~", p)
+	}
+	slots, err := p.slots(root)
+	if err != nil {
+		return 0, err
+	}
+	// the new name of each key to change, by mapping and index
+	renamed := map[*yaml.Node]map[int]string{}
+	var changed []slot
+	for _, s := range slots {
+		name := s.parent.Content[s.index].Value
+		if newName := rename(name); newName != name {
+			if renamed[s.parent] == nil {
+				renamed[s.parent] = map[int]string{}
+			}
+			renamed[s.parent][s.index] = newName
+			changed = append(changed, s)
+		}
+	}
+	counts := map[*yaml.Node]map[string]int{}
+	for _, s := range changed {
+		names := renamed[s.parent]
+		count := counts[s.parent]
+		if count == nil {
+			// count the names of the mapping's keys once renamed
+			count = map[string]int{}
+			for i := 0; i < len(s.parent.Content); i += 2 {
+				name, ok := names[i]
+				if !ok {
+					name = s.parent.Content[i].Value
+				}
+				count[name]++
+			}
+			counts[s.parent] = count
+		}
+		if name := names[s.index]; count[name] > 1 {
+			return 0, fmt.Errorf("jsonpath: cannot rename %s~ to %q: another key of the mapping has that name", s.path, name)
+		}
+	}
+	for _, s := range changed {
+		key := s.parent.Content[s.index]
+		key.Value = renamed[s.parent][s.index]
+		if key.ShortTag() != "!!str" {
+			// let a key such as 200 be resolved again from its new name
+			key.Tag = ""
+		}
+	}
+	return len(changed), nil
+}
+
+// SetCreate sets the node a singular query, such as $.components.schemas.Pet.properties.id,
+// selects within root to value, creating any mappings and sequence elements on the way to
+// it that don't exist yet, like mkdir -p. A missing member is created as a mapping if the
+// next segment selects a name, and as a sequence if it selects an index; a null in the
+// place of a mapping or sequence is replaced by one. An index past the end of a sequence
+// appends to it, padding it with nulls up to the index; padding with more than 10000
+// nulls is an error.
+//
+// The query must be singular: every segment must select a single member name or array
+// index, as in the singular queries RFC 9535 allows in comparisons. Other queries, and
+// queries that run into a node of the wrong kind, return an error and change nothing.
+func (p *JSONPath) SetCreate(root *yaml.Node, value *yaml.Node) error {
+	if value == nil {
+		return errors.New("jsonpath: cannot set a nil node")
+	}
+	path, err := p.singularPath()
+	if err != nil {
+		return err
+	}
+	ev := p.newYAMLEvaluation(root)
+	loc := ev.root
+	for i, element := range path {
+		if isNull(yamlNodeOf(loc.node)) {
+			// replace the null with the rest of the path
+			s, err := loc.slot(root)
+			if err != nil {
+				return err
+			}
+			subtree, err := createPath(path[i:], value)
+			if err != nil {
+				return err
+			}
+			s.set(root, subtree)
+			return nil
+		}
+		child, err := existingChild(loc, element)
+		if err != nil {
+			return err
+		}
+		if child == nil {
+			// add the rest of the path to the mapping or sequence
+			subtree, err := createPath(path[i+1:], value)
+			if err != nil {
+				return err
+			}
+			parent := yamlNodeOf(loc.node)
+			if element.Kind == PathElementName {
+				parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: element.Name}, subtree)
+			} else {
+				parent.Content = append(parent.Content, nullNodes(element.Index-len(parent.Content))...)
+				parent.Content = append(parent.Content, subtree)
+			}
+			return nil
+		}
+		loc = child
+	}
+	s, err := loc.slot(root)
+	if err != nil {
+		return err
+	}
+	s.set(root, value)
+	return nil
+}
+
+// singularPath returns the member names and array indices a singular query selects in
+// turn, or an error if the query isn't singular.
+func (p *JSONPath) singularPath() ([]PathElement, error) {
+	query, err := p.parseSingular()
+	if err != nil {
+		return nil, fmt.Errorf("jsonpath: %s is not a singular query: %w", p, err)
+	}
+	path := make([]PathElement, 0, len(query.segments))
+	for _, segment := range query.segments {
+		if segment.kind != segmentKindChild {
+			return nil, fmt.Errorf("jsonpath: %s is not a singular query: it selects a property name", p)
+		}
+		if segment.child.kind == segmentDotMemberName {
+			path = append(path, NameElement(segment.child.dotName))
+			continue
+		}
+		switch selector := segment.child.selectors[0]; selector.kind {
+		case selectorSubKindName:
+			path = append(path, NameElement(selector.name))
+		case selectorSubKindArrayIndex:
+			path = append(path, IndexElement(int(selector.index)))
+		default:
+			return nil, fmt.Errorf("jsonpath: %s is not a singular query: it has the non-singular selector %s", p, selector.ToString())
+		}
+	}
+	return path, nil
+}
+
+// existingChild returns the location of the member or element of the node at loc, or nil
+// if it doesn't have one, and an error if the node is of the wrong kind for element.
+func existingChild(loc *location, element PathElement) (*location, error) {
+	kind := loc.node.Kind()
+	switch {
+	case element.Kind == PathElementName && kind == NodeObject:
+		if child, ok := loc.node.Member(element.Name); ok {
+			return memberLocation(loc, element.Name, child), nil
+		}
+		return nil, nil
+	case element.Kind == PathElementIndex && kind == NodeArray:
+		i := element.Index
+		if i < 0 {
+			i += loc.node.Len()
+			if i < 0 {
+				return nil, fmt.Errorf("jsonpath: cannot create %s[%d]: the index is before the start of the array", loc.path(), element.Index)
+			}
+		}
+		if i < loc.node.Len() {
+			return elementLocation(loc, i), nil
+		}
+		if i-loc.node.Len() > maxPadding {
+			return nil, fmt.Errorf("jsonpath: cannot create %s: the index is more than %d past the end of the array", append(loc.path(), element), maxPadding)
+		}
+		return nil, nil
+	case element.Kind == PathElementName:
+		return nil, fmt.Errorf("jsonpath: cannot create %s: %s is not an object (found %s)", append(loc.path(), element), loc.path(), kind)
+	default:
+		return nil, fmt.Errorf("jsonpath: cannot create %s: %s is not an array (found %s)", append(loc.path(), element), loc.path(), kind)
+	}
+}
+
+// createPath returns the nodes path leads to within, down to value: a mapping for each
+// name, and a sequence for each index, padded with nulls.
+func createPath(path []PathElement, value *yaml.Node) (*yaml.Node, error) {
+	for i := len(path) - 1; i >= 0; i-- {
+		element := path[i]
+		if element.Kind == PathElementName {
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: element.Name}
+			value = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{key, value}}
+			continue
+		}
+		if element.Index < 0 {
+			return nil, fmt.Errorf("jsonpath: cannot create an array element at the negative index %d", element.Index)
+		}
+		if element.Index > maxPadding {
+			return nil, fmt.Errorf("jsonpath: cannot create an array element at the index %d, more than %d past the start of a new array", element.Index, maxPadding)
+		}
+		value = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: append(nullNodes(element.Index), value)}
+	}
+	return value, nil
+}
+
+// maxPadding is the most nulls SetCreate pads a sequence with to reach an index.
+const maxPadding = 10000
+
+// nullNodes returns n null nodes, to pad a sequence with.
+func nullNodes(n int) []*yaml.Node {
+	nodes := make([]*yaml.Node, n)
+	for i := range nodes {
+		nodes[i] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+	return nodes
+}
+
+// isNull reports whether n is null or empty, so that a mapping or sequence can be created
+// in its place.
+func isNull(n *yaml.Node) bool {
+	return n.Kind == 0 || n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
+
+// slot is where a selected node is held within the tree: an entry of the Content of its
+// parent mapping or sequence, or of the document node root holds. A slot whose parent is
+// nil holds root itself.
+type slot struct {
+	parent *yaml.Node
+	// index is the position of the node in parent.Content, or -1 if the node is the value
+	// of a member parent only has through a merge key. key is then that member's key.
+	index        int
+	key          *yaml.Node
+	propertyName bool
+	path         string
+	// loc is where the query selected the node
+	loc *location
+}
+
+// slots returns the slot of each node the query selects within root, once each, in the
+// order they are selected.
+func (p *JSONPath) slots(root *yaml.Node) ([]slot, error) {
+	ev := p.newYAMLEvaluation(root)
+	locations := p.ast.query(ev)
+	if ev.err != nil {
+		return nil, ev.err
+	}
+	type slotID struct {
+		parent *yaml.Node
+		index  int
+		key    *yaml.Node
+	}
+	seen := map[slotID]bool{}
+	result := make([]slot, 0, len(locations))
+	for _, loc := range locations {
+		s, err := loc.slot(root)
+		if err != nil {
+			return nil, err
+		}
+		id := slotID{s.parent, s.index, s.key}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// slot returns where the node at loc is held within root.
+func (loc *location) slot(root *yaml.Node) (slot, error) {
+	s := slot{propertyName: loc.propertyName, path: loc.path().String(), loc: loc}
+	if loc.parent == nil {
+		if root.Kind == yaml.DocumentNode && len(root.Content) == 1 {
+			s.parent = root
+		}
+		return s, nil
+	}
+	s.parent = yamlNodeOf(loc.parent.node)
+	if loc.element.Kind == PathElementIndex {
+		s.index = loc.element.Index
+		return s, nil
+	}
+	for i := 0; i+1 < len(s.parent.Content); i += 2 {
+		if s.parent.Content[i].Value != loc.element.Name {
+			continue
+		}
+		s.index = i + 1
+		if loc.propertyName {
+			s.index = i
+		}
+		return s, nil
+	}
+	// the member comes from a mapping merged into the parent
+	if loc.propertyName {
+		return s, fmt.Errorf("jsonpath: cannot change the key %s~, which is merged from another mapping", s.path)
+	}
+	s.index = -1
+	s.key = yamlNodeOf(memberKey(loc.parent.node, loc.element.Name))
+	return s, nil
+}
+
+// check returns an error if value can't be put in the slot.
+func (s slot) check(value *yaml.Node) error {
+	if s.propertyName && value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("jsonpath: cannot set the key %s~ to a non-scalar node", s.path)
+	}
+	return nil
+}
+
+// set puts value in the slot. A merged member is overridden by a member of the parent
+// itself, leaving the mapping it is merged from unchanged.
+func (s slot) set(root *yaml.Node, value *yaml.Node) {
+	switch {
+	case s.parent == nil:
+		*root = *value
+	case s.index < 0:
+		key := *s.key
+		key.Anchor = ""
+		s.parent.Content = append(s.parent.Content, &key, value)
+	default:
+		s.parent.Content[s.index] = value
+	}
+}
+
+// copyNode returns a deep copy of n. Aliases within it still refer to the same anchors.
+func copyNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	if n.Content != nil {
+		c.Content = make([]*yaml.Node, len(n.Content))
+		for i, child := range n.Content {
+			c.Content[i] = copyNode(child)
+		}
+	}
+	return &c
+}
@@ -0,0 +1,670 @@
+package jsonpath
+
+import (
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+	"strings"
+	"testing"
+)
+
+// mutationDocument parses a document for a mutation test.
+func mutationDocument(t *testing.T, document string) *yaml.Node {
+	t.Helper()
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(document), &root); err != nil {
+		t.Fatal(err)
+	}
+	return &root
+}
+
+// encodeDocument returns root encoded as YAML, for comparing with the expected document.
+func encodeDocument(t *testing.T, root *yaml.Node) string {
+	t.Helper()
+	var builder strings.Builder
+	encoder := yaml.NewEncoder(&builder)
+	if err := encoder.Encode(root); err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		document    string
+		value       string
+		opts        []config.Option
+		expected    string
+		count       int
+		errorSubstr string
+	}{
+		{
+			name:     "Mapping values",
+			input:    "$.servers[*].port",
+			document: "{servers: [{port: 80}, {port: 81}, {host: x}]}",
+			value:    "8080",
+			expected: "{servers: [{port: 8080}, {port: 8080}, {host: x}]}",
+			count:    2,
+		},
+		{
+			name:     "Array elements",
+			input:    "$.tags[?@ == 'old']",
+			document: "{tags: [old, new, old]}",
+			value:    "{name: replaced}",
+			expected: "{tags: [{name: replaced}, new, {name: replaced}]}",
+			count:    2,
+		},
+		{
+			name:     "Keys",
+			input:    "$.paths.*~",
+			document: "{paths: {/a: 1, /b: 2}}",
+			value:    "/c",
+			expected: "{paths: {/c: 1, /c: 2}}",
+			count:    2,
+		},
+		{
+			name:     "Each node once",
+			input:    "$.a[0,0,-2]",
+			document: "{a: [1, 2]}",
+			value:    "3",
+			expected: "{a: [3, 2]}",
+			count:    1,
+		},
+		{
+			name:     "Root",
+			input:    "$",
+			document: "{a: 1}",
+			value:    "[1, 2]",
+			expected: "[1, 2]",
+			count:    1,
+		},
+		{
+			name:     "No matches",
+			input:    "$.missing",
+			document: "{a: 1}",
+			value:    "2",
+			expected: "{a: 1}",
+			count:    0,
+		},
+		{
+			name:     "Through an alias",
+			input:    "$.b.x",
+			document: "{a: &a {x: 1}, b: *a}",
+			value:    "2",
+			opts:     []config.Option{config.WithAliasResolution()},
+			expected: "{a: &a {x: 2}, b: *a}",
+			count:    1,
+		},
+		{
+			name:     "Merged member is overridden",
+			input:    "$.b.x",
+			document: "{a: &a {x: 1}, b: {<<: *a, y: 2}}",
+			value:    "3",
+			opts:     []config.Option{config.WithAliasResolution()},
+			expected: "{a: &a {x: 1}, b: {!!merge <<: *a, y: 2, x: 3}}",
+			count:    1,
+		},
+		{
+			name:        "Merged key",
+			input:       "$.b.x~",
+			document:    "{a: &a {x: 1}, b: {<<: *a}}",
+			value:       "y",
+			opts:        []config.Option{config.WithAliasResolution()},
+			expected:    "{a: &a {x: 1}, b: {!!merge <<: *a}}",
+			errorSubstr: "merged from another mapping",
+		},
+		{
+			name:        "Key set to a mapping",
+			input:       "$.a~",
+			document:    "{a: 1, b: 2}",
+			value:       "{c: 3}",
+			expected:    "{a: 1, b: 2}",
+			errorSubstr: "non-scalar",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := mutationDocument(t, test.document)
+			value := mutationDocument(t, test.value).Content[0]
+			path, err := NewPath(test.input, append(test.opts, config.WithPropertyNameExtension())...)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			count, err := path.Set(root, value)
+			if test.errorSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errorSubstr) {
+					t.Errorf("Expected an error containing %q, got %v", test.errorSubstr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if count != test.count {
+				t.Errorf("Expected %d nodes to be set, got %d", test.count, count)
+			}
+			if actual := encodeDocument(t, root); actual != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSetCopies(t *testing.T) {
+	root := mutationDocument(t, "[{a: 1}, {a: 2}]")
+	value := mutationDocument(t, "{b: [1]}").Content[0]
+	path, err := NewPath("$[*].a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := path.Set(root, value); err != nil {
+		t.Fatal(err)
+	}
+	first, second := root.Content[0].Content[0].Content[1], root.Content[0].Content[1].Content[1]
+	if first != value {
+		t.Errorf("Expected the first node to be replaced by the value itself")
+	}
+	if second == value || second.Content[1] == value.Content[1] {
+		t.Errorf("Expected the second node to be replaced by a copy")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		document    string
+		opts        []config.Option
+		expected    string
+		count       int
+		errorSubstr string
+	}{
+		{
+			name:     "Several elements of a sequence",
+			input:    "$.tags[?@.deprecated]",
+			document: "{tags: [{name: a, deprecated: true}, {name: b}, {name: c, deprecated: true}, {name: d, deprecated: true}]}",
+			expected: "{tags: [{name: b}]}",
+			count:    3,
+		},
+		{
+			name:     "Elements in any order",
+			input:    "$.a[0,-1,2]",
+			document: "{a: [0, 1, 2, 3, 4]}",
+			expected: "{a: [1, 3]}",
+			count:    3,
+		},
+		{
+			name:     "Members",
+			input:    "$.paths[?@['x-internal'] == true]",
+			document: "{paths: {/a: {x-internal: true}, /b: {}, /c: {x-internal: true}}}",
+			expected: "{paths: {/b: {}}}",
+			count:    2,
+		},
+		{
+			name:     "Member selected by its key and value",
+			input:    "$['a', 'b', 'a~']",
+			document: "{a: 1, b: 2, c: 3}",
+			expected: "{c: 3}",
+			count:    2,
+		},
+		{
+			name:     "Nested matches",
+			input:    "$..x",
+			document: "{x: {x: 1}, y: [{x: 2}, 3]}",
+			expected: "{y: [{}, 3]}",
+			count:    3,
+		},
+		{
+			name:     "No matches",
+			input:    "$.missing",
+			document: "{a: 1}",
+			expected: "{a: 1}",
+		},
+		{
+			name:        "Root",
+			input:       "$",
+			document:    "{a: 1}",
+			expected:    "{a: 1}",
+			errorSubstr: "root",
+		},
+		{
+			name:        "Merged member",
+			input:       "$.b[*]",
+			document:    "{a: &a {x: 1}, b: {<<: *a, y: 2}}",
+			opts:        []config.Option{config.WithAliasResolution()},
+			expected:    "{a: &a {x: 1}, b: {!!merge <<: *a, y: 2}}",
+			errorSubstr: "merged from another mapping",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := mutationDocument(t, test.document)
+			path, err := NewPath(test.input, append(test.opts, config.WithPropertyNameExtension())...)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			count, err := path.Delete(root)
+			if test.errorSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errorSubstr) {
+					t.Errorf("Expected an error containing %q, got %v", test.errorSubstr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if count != test.count {
+				t.Errorf("Expected %d nodes to be deleted, got %d", test.count, count)
+			}
+			if actual := encodeDocument(t, root); actual != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSetCreate(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		document    string
+		opts        []config.Option
+		expected    string
+		errorSubstr string
+	}{
+		{
+			name:     "Existing node",
+			input:    "$.info.title",
+			document: "{info: {title: old}}",
+			expected: "{info: {title: new}}",
+		},
+		{
+			name:     "Missing mappings",
+			input:    "$.components.schemas.Pet.properties['x-id']",
+			document: "{components: {schemas: {Tag: {}}}}",
+			expected: "{components: {schemas: {Tag: {}, Pet: {properties: {x-id: new}}}}}",
+		},
+		{
+			name:     "Missing sequence elements",
+			input:    "$.servers[2].url",
+			document: "{servers: [{url: a}]}",
+			expected: "{servers: [{url: a}, null, {url: new}]}",
+		},
+		{
+			name:     "Missing sequence",
+			input:    "$.tags[1]",
+			document: "{}",
+			expected: "{tags: [null, new]}",
+		},
+		{
+			name:     "Negative index",
+			input:    "$.tags[-1].name",
+			document: "{tags: [{name: a}, {name: b}]}",
+			expected: "{tags: [{name: a}, {name: new}]}",
+		},
+		{
+			name:     "Null replaced",
+			input:    "$.a.b.c",
+			document: "{a: null}",
+			expected: "{a: {b: {c: new}}}",
+		},
+		{
+			name:     "Empty document",
+			input:    "$.a",
+			document: "",
+			expected: "a: new",
+		},
+		{
+			name:     "Merged member is overridden",
+			input:    "$.b.x",
+			document: "{a: &a {x: 1}, b: {<<: *a}}",
+			opts:     []config.Option{config.WithAliasResolution()},
+			expected: "{a: &a {x: 1}, b: {!!merge <<: *a, x: new}}",
+		},
+		{
+			name:        "Wrong kind",
+			input:       "$.a.b.c",
+			document:    "{a: {b: [1]}}",
+			expected:    "{a: {b: [1]}}",
+			errorSubstr: "$['a']['b'] is not an object (found array)",
+		},
+		{
+			name:        "Negative index on a missing array",
+			input:       "$.a.b[-1]",
+			document:    "{a: {}}",
+			expected:    "{a: {}}",
+			errorSubstr: "negative index -1",
+		},
+		{
+			name:        "Negative index before the start",
+			input:       "$.a[-3]",
+			document:    "{a: [1, 2]}",
+			expected:    "{a: [1, 2]}",
+			errorSubstr: "before the start of the array",
+		},
+		{
+			name:        "Index far past the end",
+			input:       "$.a[9007199254740991]",
+			document:    "{a: []}",
+			expected:    "{a: []}",
+			errorSubstr: "the index is more than 10000 past the end of the array",
+		},
+		{
+			name:        "Index far past the start of a missing array",
+			input:       "$.a.b[10001]",
+			document:    "{a: {}}",
+			expected:    "{a: {}}",
+			errorSubstr: "at the index 10001, more than 10000 past the start of a new array",
+		},
+		{
+			name:        "Index far past the start of an array replacing a null",
+			input:       "$.a[9007199254740991]",
+			document:    "{a: null}",
+			expected:    "{a: null}",
+			errorSubstr: "at the index 9007199254740991",
+		},
+		{
+			name:        "Wildcard",
+			input:       "$.a.*",
+			document:    "{a: {}}",
+			expected:    "{a: {}}",
+			errorSubstr: "not a singular query",
+		},
+		{
+			name:        "Several selectors",
+			input:       "$.a['b','c']",
+			document:    "{a: {}}",
+			expected:    "{a: {}}",
+			errorSubstr: "not a singular query",
+		},
+		{
+			name:        "Descendants",
+			input:       "$..a",
+			document:    "{a: {}}",
+			expected:    "{a: {}}",
+			errorSubstr: "not a singular query",
+		},
+		{
+			name:        "Filter",
+			input:       "$.a[?@.b]",
+			document:    "{a: {}}",
+			expected:    "{a: {}}",
+			errorSubstr: "not a singular query: it has the non-singular selector ?@.b",
+		},
+		{
+			name:        "Bracketed wildcard",
+			input:       "$.a[*]",
+			document:    "{a: {}}",
+			expected:    "{a: {}}",
+			errorSubstr: "not a singular query: Error at line 1, column 4: unexpected wildcard in singular query",
+		},
+		{
+			name:        "Slice",
+			input:       "$.a[0:1]",
+			document:    "{a: []}",
+			expected:    "{a: []}",
+			errorSubstr: "not a singular query: Error at line 1, column 4: unexpected slice in singular query",
+		},
+		{
+			name:        "Property name",
+			input:       "$.a~",
+			document:    "{a: {}}",
+			expected:    "{a: {}}",
+			errorSubstr: "not a singular query",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := mutationDocument(t, test.document)
+			path, err := NewPath(test.input, append(test.opts, config.WithPropertyNameExtension())...)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			err = path.SetCreate(root, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "new"})
+			if test.errorSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errorSubstr) {
+					t.Errorf("Expected an error containing %q, got %v", test.errorSubstr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if actual := encodeDocument(t, root); actual != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestInsert(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		document    string
+		insert      func(path *JSONPath, root *yaml.Node, value *yaml.Node) (int, error)
+		expected    string
+		count       int
+		errorSubstr string
+	}{
+		{
+			name:     "Append",
+			input:    "$.servers",
+			document: "{servers: [{url: a}]}",
+			insert:   (*JSONPath).Append,
+			expected: "{servers: [{url: a}, new]}",
+			count:    1,
+		},
+		{
+			name:     "Append to several sequences",
+			input:    "$.paths.*.tags",
+			document: "{paths: {/a: {tags: []}, /b: {tags: [x]}}}",
+			insert:   (*JSONPath).Append,
+			expected: "{paths: {/a: {tags: [new]}, /b: {tags: [x, new]}}}",
+			count:    2,
+		},
+		{
+			name:     "Prepend",
+			input:    "$.tags",
+			document: "{tags: [a, b]}",
+			insert:   (*JSONPath).Prepend,
+			expected: "{tags: [new, a, b]}",
+			count:    1,
+		},
+		{
+			name:     "Insert",
+			input:    "$.tags",
+			document: "{tags: [a, b, c]}",
+			insert: func(path *JSONPath, root *yaml.Node, value *yaml.Node) (int, error) {
+				return path.Insert(root, 1, value)
+			},
+			expected: "{tags: [a, new, b, c]}",
+			count:    1,
+		},
+		{
+			name:     "Insert at a negative index",
+			input:    "$.tags",
+			document: "{tags: [a, b, c]}",
+			insert: func(path *JSONPath, root *yaml.Node, value *yaml.Node) (int, error) {
+				return path.Insert(root, -1, value)
+			},
+			expected: "{tags: [a, b, new, c]}",
+			count:    1,
+		},
+		{
+			name:     "Insert at the length",
+			input:    "$.tags",
+			document: "{tags: [a]}",
+			insert: func(path *JSONPath, root *yaml.Node, value *yaml.Node) (int, error) {
+				return path.Insert(root, 1, value)
+			},
+			expected: "{tags: [a, new]}",
+			count:    1,
+		},
+		{
+			name:     "Insert out of range",
+			input:    "$.*",
+			document: "{a: [1, 2, 3], b: [1]}",
+			insert: func(path *JSONPath, root *yaml.Node, value *yaml.Node) (int, error) {
+				return path.Insert(root, -3, value)
+			},
+			expected:    "{a: [1, 2, 3], b: [1]}",
+			errorSubstr: "out of range for its 1 elements",
+		},
+		{
+			name:        "Insert into a mapping",
+			input:       "$.a",
+			document:    "{a: {b: 1}}",
+			insert:      (*JSONPath).Append,
+			expected:    "{a: {b: 1}}",
+			errorSubstr: "not an array (found object)",
+		},
+		{
+			name:     "Before several elements",
+			input:    "$.tags[?@ != 'b']",
+			document: "{tags: [a, b, c]}",
+			insert:   (*JSONPath).InsertBefore,
+			expected: "{tags: [new, a, b, new, c]}",
+			count:    2,
+		},
+		{
+			name:     "After several elements",
+			input:    "$.tags[2,0,-1]",
+			document: "{tags: [a, b, c]}",
+			insert:   (*JSONPath).InsertAfter,
+			expected: "{tags: [a, new, b, c, new]}",
+			count:    2,
+		},
+		{
+			name:        "Beside a member",
+			input:       "$.a",
+			document:    "{a: [1]}",
+			insert:      (*JSONPath).InsertAfter,
+			expected:    "{a: [1]}",
+			errorSubstr: "not an array element",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := mutationDocument(t, test.document)
+			path, err := NewPath(test.input)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			count, err := test.insert(path, root, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "new"})
+			if test.errorSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errorSubstr) {
+					t.Errorf("Expected an error containing %q, got %v", test.errorSubstr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if count != test.count {
+				t.Errorf("Expected %d insertions, got %d", test.count, count)
+			}
+			if actual := encodeDocument(t, root); actual != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRenameKeys(t *testing.T) {
+	prefix := func(old string) string {
+		return "Old" + old
+	}
+	tests := []struct {
+		name        string
+		input       string
+		document    string
+		rename      func(string) string
+		expected    string
+		count       int
+		errorSubstr string
+	}{
+		{
+			name:     "Mapping keys",
+			input:    "$.components.schemas.*~",
+			document: "{components: {schemas: {Pet: {}, Tag: {}}}}",
+			rename:   prefix,
+			expected: "{components: {schemas: {OldPet: {}, OldTag: {}}}}",
+			count:    2,
+		},
+		{
+			name:     "Unchanged keys",
+			input:    "$.*~",
+			document: "{a: 1, B: 2}",
+			rename:   strings.ToLower,
+			expected: "{a: 1, b: 2}",
+			count:    1,
+		},
+		{
+			name:     "Swapped keys",
+			input:    "$.*~",
+			document: "{a: 1, b: 2}",
+			rename: func(old string) string {
+				return map[string]string{"a": "b", "b": "a"}[old]
+			},
+			expected: "{b: 1, a: 2}",
+			count:    2,
+		},
+		{
+			name:     "Non-string key",
+			input:    "$.responses['200']~",
+			document: "{responses: {200: {}, '404': {}}}",
+			rename: func(string) string {
+				return "201"
+			},
+			expected: "{responses: {201: {}, '404': {}}}",
+			count:    1,
+		},
+		{
+			name:        "Collision with a sibling",
+			input:       "$.schemas.Pet~",
+			document:    "{schemas: {Pet: {}, OldPet: {}}}",
+			rename:      prefix,
+			expected:    "{schemas: {Pet: {}, OldPet: {}}}",
+			errorSubstr: `cannot rename $['schemas']['Pet']~ to "OldPet"`,
+		},
+		{
+			name:     "Collision between renamed keys",
+			input:    "$.*~",
+			document: "{a: 1, A: 2}",
+			rename:   strings.ToUpper,
+			expected: "{a: 1, A: 2}",
+			// A is unchanged, but a would take its name
+			errorSubstr: "another key of the mapping",
+		},
+		{
+			name:        "Query not ending in ~",
+			input:       "$.*",
+			document:    "{a: 1}",
+			rename:      prefix,
+			expected:    "{a: 1}",
+			errorSubstr: "does not end in ~",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := mutationDocument(t, test.document)
+			path, err := NewPath(test.input, config.WithPropertyNameExtension())
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			count, err := path.RenameKeys(root, test.rename)
+			if test.errorSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errorSubstr) {
+					t.Errorf("Expected an error containing %q, got %v", test.errorSubstr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if count != test.count {
+				t.Errorf("Expected %d keys to be renamed, got %d", test.count, count)
+			}
+			if actual := encodeDocument(t, root); actual != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"iter"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+)
+
+// NodeKind is the JSON type of a Node.
+type NodeKind int
+
+const (
+	// NodeOther is a value with no JSON interpretation, e.g. a YAML scalar with a
+	// custom tag. It only ever equals other values of the same kind.
+	NodeOther NodeKind = iota
+	NodeNull
+	NodeBool
+	NodeNumber
+	NodeString
+	NodeArray
+	NodeObject
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case NodeNull:
+		return "null"
+	case NodeBool:
+		return "bool"
+	case NodeNumber:
+		return "number"
+	case NodeString:
+		return "string"
+	case NodeArray:
+		return "array"
+	case NodeObject:
+		return "object"
+	}
+	return "other"
+}
+
+// Node is the document model queries are evaluated against. Implementing it lets a query
+// run directly over any in-memory document type; NewYAMLNode and NewValueNode adapt
+// *yaml.Node trees and plain Go values respectively.
+type Node interface {
+	Kind() NodeKind
+	// Value returns the value of a scalar: nil, a bool, a string, any of Go's number types,
+	// a json.Number or a *big.Int. For NodeOther it may be anything, and is compared with
+	// reflect.DeepEqual.
+	Value() any
+	// Len returns the number of elements of an array, or of members of an object.
+	Len() int
+	// Index returns the i'th element of an array, where 0 <= i < Len().
+	Index(i int) Node
+	// Members yields the name and value of each member of an object, in document order.
+	Members() iter.Seq2[string, Node]
+	// Member returns the value of the named member of an object.
+	Member(name string) (Node, bool)
+	// Identity returns a comparable value that is equal for two Nodes only when they are
+	// the same node of the document. It is used to make descendant results unique.
+	Identity() any
+}
+
+// Keyer is implemented by Nodes whose member names are nodes in their own right, such as
+// the keys of a YAML mapping. Key is what the "~" extension selects; for other Nodes, it
+// selects a string node holding the name.
+type Keyer interface {
+	Key(name string) Node
+}
+
+// memberKey returns the node holding the name of the named member of parent.
+func memberKey(parent Node, name string) Node {
+	if keyer, ok := parent.(Keyer); ok {
+		if key := keyer.Key(name); key != nil {
+			return key
+		}
+	}
+	return nameNode{parent: parent.Identity(), name: name}
+}
+
+// nameNode is a member name, as selected by "~" from a Node that isn't a Keyer.
+type nameNode struct {
+	parent any
+	name   string
+}
+
+func (n nameNode) Kind() NodeKind                   { return NodeString }
+func (n nameNode) Value() any                       { return n.name }
+func (n nameNode) Len() int                         { return 0 }
+func (n nameNode) Index(int) Node                   { return nil }
+func (n nameNode) Members() iter.Seq2[string, Node] { return func(func(string, Node) bool) {} }
+func (n nameNode) Member(string) (Node, bool)       { return nil, false }
+func (n nameNode) Identity() any                    { return n }
+
+func nodeToLiteral(n Node) literal {
+	switch n.Kind() {
+	case NodeNull:
+		b := true
+		return literal{null: &b}
+	case NodeBool:
+		if b, ok := n.Value().(bool); ok {
+			return literal{bool: &b}
+		}
+	case NodeString:
+		if s, ok := n.Value().(string); ok {
+			return literal{string: &s}
+		}
+	case NodeNumber:
+		return numberToLiteral(n.Value())
+	default:
+		if t, ok := n.Value().(time.Time); ok {
+			return literal{time: &t}
+		}
+		return literal{node: n}
+	}
+	return literal{}
+}
+
+// numberToLiteral converts any of Go's number types, *big.Int and json.Number to a number
+// literal. Integers are never converted to floats, so that they are compared exactly.
+func numberToLiteral(value any) literal {
+	var f float64
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64:
+		return intToLiteral(reflect.ValueOf(v).Int())
+	case uint, uint8, uint16, uint32, uint64, uintptr:
+		u64 := reflect.ValueOf(v).Uint()
+		if u64 <= math.MaxInt64 {
+			return intToLiteral(int64(u64))
+		}
+		return literal{bigInt: new(big.Int).SetUint64(u64)}
+	case *big.Int:
+		if v == nil {
+			return literal{}
+		}
+		return bigIntToLiteral(v)
+	case float32:
+		f = float64(v)
+	case float64:
+		f = v
+	case json.Number:
+		if i, ok := new(big.Int).SetString(string(v), 10); ok {
+			return bigIntToLiteral(i)
+		}
+		var err error
+		if f, err = v.Float64(); err != nil {
+			return literal{}
+		}
+	default:
+		return literal{}
+	}
+	return literal{float64: &f}
+}
+
+// intToLiteral returns an integer literal, held by a big.Int beyond ±MaxSafeFloat.
+func intToLiteral(i int64) literal {
+	if i > MaxSafeFloat || i < -MaxSafeFloat {
+		return literal{bigInt: big.NewInt(i)}
+	}
+	n := int(i)
+	return literal{integer: &n}
+}
+
+func bigIntToLiteral(i *big.Int) literal {
+	if i.IsInt64() {
+		return intToLiteral(i.Int64())
+	}
+	return literal{bigInt: i}
+}
+
+// equalsNode compares two values as RFC 9535 does: arrays element by element, objects by
+// their set of members whatever their order, and numbers by value, so 1 equals 1.0. A node
+// equals itself without being compared. YAML aliases are compared as the node they refer
+// to only with config.WithAliasResolution, which counts them towards its limit.
+func equalsNode(a Node, b Node) bool {
+	return nodesEqual(a, b, nil)
+}
+
+// nodePair is a pair of nodes being compared, by their identities.
+type nodePair struct {
+	a, b any
+}
+
+// nodesEqual is equalsNode, given the pairs of arrays and objects its callers are
+// comparing. Aliases can make a YAML document cyclic, in which case comparing the same
+// pair again is assumed to find them equal, as nothing has found them to differ yet.
+func nodesEqual(a Node, b Node, comparing map[nodePair]bool) bool {
+	if a.Identity() == b.Identity() {
+		return true
+	}
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	switch a.Kind() {
+	case NodeArray, NodeObject:
+		if a.Len() != b.Len() {
+			return false
+		}
+		pair := nodePair{a.Identity(), b.Identity()}
+		if comparing[pair] {
+			return true
+		}
+		if comparing == nil {
+			comparing = map[nodePair]bool{}
+		}
+		comparing[pair] = true
+		defer delete(comparing, pair)
+		if a.Kind() == NodeArray {
+			for i := range a.Len() {
+				if !nodesEqual(a.Index(i), b.Index(i), comparing) {
+					return false
+				}
+			}
+			return true
+		}
+		for name, value := range a.Members() {
+			other, ok := b.Member(name)
+			if !ok || !nodesEqual(value, other, comparing) {
+				return false
+			}
+		}
+		return true
+	case NodeOther:
+		if t, ok := a.Value().(time.Time); ok {
+			other, ok := b.Value().(time.Time)
+			return ok && t.Equal(other)
+		}
+		return unresolvedTag(a) == unresolvedTag(b) && reflect.DeepEqual(a.Value(), b.Value())
+	}
+	return nodeToLiteral(a).Equals(nodeToLiteral(b))
+}
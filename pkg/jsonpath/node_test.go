@@ -0,0 +1,183 @@
+package jsonpath
+
+import (
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+	"iter"
+	"reflect"
+	"testing"
+)
+
+// testNode is a minimal document model, standing in for a caller's own document type.
+// Objects keep their members in insertion order.
+type testNode struct {
+	kind     NodeKind
+	value    any
+	names    []string
+	children []*testNode
+}
+
+func testScalar(kind NodeKind, value any) *testNode {
+	return &testNode{kind: kind, value: value}
+}
+
+func testArray(elements ...*testNode) *testNode {
+	return &testNode{kind: NodeArray, children: elements}
+}
+
+func testObject(members ...any) *testNode {
+	n := &testNode{kind: NodeObject}
+	for i := 0; i < len(members); i += 2 {
+		n.names = append(n.names, members[i].(string))
+		n.children = append(n.children, members[i+1].(*testNode))
+	}
+	return n
+}
+
+func (n *testNode) Kind() NodeKind   { return n.kind }
+func (n *testNode) Value() any       { return n.value }
+func (n *testNode) Len() int         { return len(n.children) }
+func (n *testNode) Index(i int) Node { return n.children[i] }
+func (n *testNode) Identity() any    { return n }
+
+func (n *testNode) Members() iter.Seq2[string, Node] {
+	return func(yield func(string, Node) bool) {
+		for i, name := range n.names {
+			if !yield(name, n.children[i]) {
+				return
+			}
+		}
+	}
+}
+
+func (n *testNode) Member(name string) (Node, bool) {
+	for i, other := range n.names {
+		if other == name {
+			return n.children[i], true
+		}
+	}
+	return nil, false
+}
+
+func TestQueryNode(t *testing.T) {
+	root := testObject(
+		"zebra", testScalar(NodeNumber, int64(1)),
+		"apple", testArray(
+			testObject("name", testScalar(NodeString, "a"), "ok", testScalar(NodeBool, true)),
+			testObject("name", testScalar(NodeString, "b"), "ok", testScalar(NodeNull, nil)),
+		),
+	)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []any
+	}{
+		{
+			name:     "Members keep document order",
+			input:    "$.*~",
+			expected: []any{"zebra", "apple"},
+		},
+		{
+			name:     "Filter",
+			input:    "$.apple[?@.ok == true].name",
+			expected: []any{"a"},
+		},
+		{
+			name:     "Number conversion",
+			input:    "$[?@ == 1]",
+			expected: []any{int64(1)},
+		},
+		{
+			name:     "Descendants",
+			input:    "$..name",
+			expected: []any{"a", "b"},
+		},
+		{
+			name:     "Function",
+			input:    "$[?length(@) == 2][1].name",
+			expected: []any{"b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := NewPath(test.input, config.WithPropertyNameExtension())
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			actual := []any{}
+			for _, node := range path.QueryNode(root) {
+				actual = append(actual, node.Value())
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("Expected:\n%#v\nGot:\n%#v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAllNodes(t *testing.T) {
+	root := testObject("a", testArray(testScalar(NodeString, "x"), testScalar(NodeString, "y")))
+	path, err := NewPath("$.a[*]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for normalized, node := range path.AllNodes(root) {
+		if resolved := normalized.ResolveNode(root); resolved != node {
+			t.Errorf("%s resolved to %v, expected %v", normalized, resolved, node)
+		}
+	}
+	var paths []string
+	for normalized := range path.AllNodes(root) {
+		paths = append(paths, normalized.String())
+	}
+	if expected := []string{"$['a'][0]", "$['a'][1]"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+}
+
+func TestEqualsNode(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		aliases  bool
+		expected bool
+	}{
+		{name: "Objects in a different member order", yaml: "a: {x: 1, y: 2}\nb: {y: 2, x: 1}", expected: true},
+		{name: "Objects with different members", yaml: "a: {x: 1, y: 2}\nb: {x: 1, z: 2}", expected: false},
+		{name: "Object with an extra member", yaml: "a: {x: 1}\nb: {x: 1, y: 2}", expected: false},
+		{name: "Arrays are ordered", yaml: "a: [1, 2]\nb: [2, 1]", expected: false},
+		{name: "Nested numbers by value", yaml: "a: [{x: 1}, 0x10]\nb: [{x: 1.0}, 16]", expected: true},
+		{name: "Nested strings are not numbers", yaml: "a: [1]\nb: ['1']", expected: false},
+		{name: "Empty object and empty array", yaml: "a: {}\nb: []", expected: false},
+		{name: "Alias and its anchor", yaml: "a: &x {x: [1, 2]}\nb: *x", aliases: true, expected: true},
+		{name: "Nested alias", yaml: "x: &x [1, 2]\na: {k: *x}\nb: {k: [1, 2]}", aliases: true, expected: true},
+		{name: "Cyclic documents", yaml: "a: &a [*a]\nb: &b [*b]", aliases: true, expected: true},
+		{name: "Alias and its anchor, without alias resolution", yaml: "a: &x {x: [1, 2]}\nb: *x", expected: false},
+		{name: "Nested alias, without alias resolution", yaml: "x: &x [1, 2]\na: {k: *x}\nb: {k: [1, 2]}", expected: false},
+		{name: "Aliases of the same anchor, without alias resolution", yaml: "x: &x [1, 2]\na: [*x]\nb: [*x]", expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte(test.yaml), &root); err != nil {
+				t.Fatal(err)
+			}
+			document := NewYAMLNode(root.Content[0])
+			if test.aliases {
+				path, _ := NewPath("$", config.WithAliasResolution())
+				document = path.newYAMLEvaluation(root.Content[0]).root.node
+			}
+			a, _ := document.Member("a")
+			b, _ := document.Member("b")
+			if actual := equalsNode(a, b); actual != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, actual)
+			}
+			if actual := equalsNode(b, a); actual != test.expected {
+				t.Errorf("Expected %v in reverse, got %v", test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package jsonpath
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// Match is a single node selected by a query, together with where it was found.
+type Match struct {
+	// Node is the selected node.
+	Node *yaml.Node
+	// Path is the Normalized Path of Node. For keys selected with the "~" property name
+	// extension, it is the path of the member the key belongs to.
+	Path NormalizedPath
+	// Parent is the mapping or sequence node containing Node, or nil if Node is the root.
+	Parent *yaml.Node
+	// Key is the member name of Node when Parent is a mapping.
+	Key string
+	// Index is the position of Node when Parent is a sequence.
+	Index int
+	// PropertyName is true when Node is a mapping key selected with the "~" extension.
+	PropertyName bool
+}
+
+// NodeList is the result of a query: the selected nodes in order, with their locations.
+type NodeList []Match
+
+// Nodes returns the selected nodes.
+func (l NodeList) Nodes() []*yaml.Node {
+	result := make([]*yaml.Node, len(l))
+	for i, match := range l {
+		result[i] = match.Node
+	}
+	return result
+}
+
+// Paths returns the Normalized Paths of the selected nodes.
+func (l NodeList) Paths() []NormalizedPath {
+	result := make([]NormalizedPath, len(l))
+	for i, match := range l {
+		result[i] = match.Path
+	}
+	return result
+}
+
+func (loc *location) match() Match {
+	m := Match{Node: yamlNodeOf(loc.node), Path: loc.path(), PropertyName: loc.propertyName}
+	if loc.parent != nil {
+		m.Parent = yamlNodeOf(loc.parent.node)
+		if loc.element.Kind == PathElementName {
+			m.Key = loc.element.Name
+		} else {
+			m.Index = loc.element.Index
+		}
+	}
+	return m
+}
+
+func toNodeList(locations []*location) NodeList {
+	result := make(NodeList, len(locations))
+	for i, loc := range locations {
+		result[i] = loc.match()
+	}
+	return result
+}
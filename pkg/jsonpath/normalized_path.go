@@ -0,0 +1,307 @@
+package jsonpath
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// NormalizedPath is a JSONPath Normalized Path (RFC 9535 §2.7): the sequence of member
+// names and array indices that identifies a single node within a document.
+type NormalizedPath []PathElement
+
+type PathElementKind int
+
+const (
+	PathElementName  PathElementKind = iota // ['name']
+	PathElementIndex                        // [0]
+)
+
+// PathElement is a single step of a NormalizedPath: either a member name or an array index.
+type PathElement struct {
+	Kind  PathElementKind
+	Name  string
+	Index int
+}
+
+// NameElement returns a PathElement selecting the object member with the given name.
+func NameElement(name string) PathElement {
+	return PathElement{Kind: PathElementName, Name: name}
+}
+
+// IndexElement returns a PathElement selecting the array element at the given index.
+func IndexElement(index int) PathElement {
+	return PathElement{Kind: PathElementIndex, Index: index}
+}
+
+func (e PathElement) String() string {
+	if e.Kind == PathElementIndex {
+		return "[" + strconv.Itoa(e.Index) + "]"
+	}
+	return "['" + escapeQuoted(e.Name, '\'') + "']"
+}
+
+// String returns the path in its canonical form, e.g. $['paths']['/pets']['get'].
+func (p NormalizedPath) String() string {
+	builder := strings.Builder{}
+	builder.WriteString("$")
+	for _, element := range p {
+		builder.WriteString(element.String())
+	}
+	return builder.String()
+}
+
+// ParseNormalizedPath parses a Normalized Path. Only the normalized-path grammar of
+// RFC 9535 Figure 3 is accepted: any other JSONPath query, even a singular one, or a
+// path that is not escaped canonically, is an error.
+func ParseNormalizedPath(input string) (NormalizedPath, error) {
+	if !strings.HasPrefix(input, "$") {
+		return nil, normalizedPathError(input, 0, "expected '$'")
+	}
+	result := NormalizedPath{}
+	pos := 1
+	for pos < len(input) {
+		if input[pos] != '[' {
+			return nil, normalizedPathError(input, pos, "expected '['")
+		}
+		pos++
+		var element PathElement
+		var err error
+		if pos < len(input) && input[pos] == '\'' {
+			element, pos, err = parseNormalName(input, pos+1)
+		} else {
+			element, pos, err = parseNormalIndex(input, pos)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if pos >= len(input) || input[pos] != ']' {
+			return nil, normalizedPathError(input, pos, "expected ']'")
+		}
+		pos++
+		result = append(result, element)
+	}
+	return result, nil
+}
+
+// parseNormalName parses a normal-name-selector starting just after its opening quote,
+// returning the position of the closing quote's successor.
+func parseNormalName(input string, pos int) (PathElement, int, error) {
+	b := strings.Builder{}
+	for pos < len(input) {
+		r, size := utf8.DecodeRuneInString(input[pos:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			return PathElement{}, pos, normalizedPathError(input, pos, "invalid UTF-8")
+		case r == '\'':
+			return NameElement(b.String()), pos + 1, nil
+		case r < 0x20:
+			return PathElement{}, pos, normalizedPathError(input, pos, "unescaped control character")
+		case r == '\\':
+			if pos+1 >= len(input) {
+				return PathElement{}, pos, normalizedPathError(input, pos, "unterminated escape")
+			}
+			switch input[pos+1] {
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '\'':
+				b.WriteByte('\'')
+			case '\\':
+				b.WriteByte('\\')
+			case 'u':
+				c, ok := parseNormalHexChar(input[pos+2:])
+				if !ok {
+					return PathElement{}, pos, normalizedPathError(input, pos, "invalid unicode escape")
+				}
+				b.WriteByte(c)
+				pos += 4
+			default:
+				return PathElement{}, pos, normalizedPathError(input, pos, "invalid escape")
+			}
+			pos += 2
+		default:
+			b.WriteRune(r)
+			pos += size
+		}
+	}
+	return PathElement{}, pos, normalizedPathError(input, pos, "unterminated name")
+}
+
+// parseNormalHexChar parses the four hex digits of a \u escape, which in a Normalized
+// Path may only encode the control characters that have no shorter escape.
+func parseNormalHexChar(input string) (byte, bool) {
+	if len(input) < 4 || input[0] != '0' || input[1] != '0' {
+		return 0, false
+	}
+	hexDigit := func(c byte) (byte, bool) {
+		switch {
+		case c >= '0' && c <= '9':
+			return c - '0', true
+		case c >= 'a' && c <= 'f':
+			return c - 'a' + 10, true
+		}
+		return 0, false
+	}
+	high, ok := hexDigit(input[2])
+	if !ok || high > 1 {
+		return 0, false
+	}
+	low, ok := hexDigit(input[3])
+	if !ok {
+		return 0, false
+	}
+	c := high<<4 | low
+	switch c {
+	case '\b', '\t', '\n', '\f', '\r':
+		return 0, false
+	}
+	return c, true
+}
+
+// parseNormalIndex parses a normal-index-selector: a non-negative integer without leading zeros.
+func parseNormalIndex(input string, pos int) (PathElement, int, error) {
+	start := pos
+	for pos < len(input) && input[pos] >= '0' && input[pos] <= '9' {
+		pos++
+	}
+	if pos == start {
+		return PathElement{}, pos, normalizedPathError(input, pos, "expected name or index")
+	}
+	if input[start] == '0' && pos-start > 1 {
+		return PathElement{}, start, normalizedPathError(input, start, "leading zeros in index")
+	}
+	i, err := strconv.Atoi(input[start:pos])
+	if err != nil {
+		return PathElement{}, start, normalizedPathError(input, start, "index out of range")
+	}
+	return IndexElement(i), pos, nil
+}
+
+func normalizedPathError(input string, pos int, msg string) error {
+	return fmt.Errorf("invalid normalized path %q at position %d: %s", input, pos, msg)
+}
+
+// MarshalText implements encoding.TextMarshaler. It is an error for a name not to be valid
+// UTF-8, as String replaces its invalid bytes, so the text wouldn't read back as the path.
+func (p NormalizedPath) MarshalText() ([]byte, error) {
+	for _, element := range p {
+		if element.Kind == PathElementName && !utf8.ValidString(element.Name) {
+			return nil, fmt.Errorf("jsonpath: cannot marshal %s: the name %q is not valid UTF-8", p, element.Name)
+		}
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *NormalizedPath) UnmarshalText(text []byte) error {
+	parsed, err := ParseNormalizedPath(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// Resolve returns the node the path identifies within root, or nil if there is none.
+// Each step is looked up directly, without evaluating a query.
+func (p NormalizedPath) Resolve(root *yaml.Node) *yaml.Node {
+	loc := p.resolve(rootLocation(root, defaultConfig))
+	if loc == nil {
+		return nil
+	}
+	return yamlNodeOf(loc.node)
+}
+
+// ResolveNode is like Resolve, for any document model implementing Node.
+func (p NormalizedPath) ResolveNode(root Node) Node {
+	loc := p.resolve(&location{node: root})
+	if loc == nil {
+		return nil
+	}
+	return loc.node
+}
+
+func (p NormalizedPath) resolve(root *location) *location {
+	current := root
+	for _, element := range p {
+		current = element.resolve(current)
+		if current == nil {
+			return nil
+		}
+	}
+	return current
+}
+
+func (e PathElement) resolve(value *location) *location {
+	switch e.Kind {
+	case PathElementName:
+		return member(value, e.Name)
+	case PathElementIndex:
+		if value.node.Kind() != NodeArray || e.Index < 0 || e.Index >= value.node.Len() {
+			return nil
+		}
+		return elementLocation(value, e.Index)
+	}
+	return nil
+}
+
+// escapeQuoted escapes value for a string literal in the given quotes, so that it reads
+// back as the same string: the quote, backslashes and control characters are escaped. In
+// single quotes, this is a normal-name-selector (RFC 9535 §2.7). A string literal can only
+// hold Unicode, so bytes that aren't valid UTF-8 are written as U+FFFD.
+func escapeQuoted(value string, quote byte) string {
+	const hex = "0123456789abcdef"
+	b := strings.Builder{}
+	for _, r := range value {
+		switch r {
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\\':
+			b.WriteString(`\\`)
+		case rune(quote):
+			b.WriteByte('\\')
+			b.WriteByte(quote)
+		default:
+			if r < 0x20 {
+				b.WriteString(`\u00`)
+				b.WriteByte(hex[r>>4])
+				b.WriteByte(hex[r&0xf])
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// path returns the Normalized Path of the node at loc.
+func (loc *location) path() NormalizedPath {
+	depth := 0
+	for l := loc; l.parent != nil; l = l.parent {
+		depth++
+	}
+	result := make(NormalizedPath, depth)
+	for l := loc; l.parent != nil; l = l.parent {
+		depth--
+		result[depth] = l.element
+	}
+	return result
+}
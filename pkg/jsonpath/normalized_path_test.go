@@ -0,0 +1,213 @@
+package jsonpath
+
+import (
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizedPathString(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     NormalizedPath
+		expected string
+	}{
+		{
+			name:     "Root",
+			path:     NormalizedPath{},
+			expected: "$",
+		},
+		{
+			name:     "Names and indices",
+			path:     NormalizedPath{NameElement("paths"), NameElement("/pets"), NameElement("get"), IndexElement(0)},
+			expected: "$['paths']['/pets']['get'][0]",
+		},
+		{
+			name:     "Escapable characters",
+			path:     NormalizedPath{NameElement("\b\f\n\r\t'\\")},
+			expected: `$['\b\f\n\r\t\'\\']`,
+		},
+		{
+			name:     "Other control characters",
+			path:     NormalizedPath{NameElement("\x00\x0b\x1f")},
+			expected: `$['\u0000\u000b\u001f']`,
+		},
+		{
+			name:     "Unescaped characters",
+			path:     NormalizedPath{NameElement(`"/ü☺`)},
+			expected: `$['"/ü☺']`,
+		},
+		{
+			name:     "Invalid UTF-8",
+			path:     NormalizedPath{NameElement("a\xffb")},
+			expected: "$['a\uFFFDb']",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := test.path.String(); actual != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseNormalizedPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected NormalizedPath
+		invalid  bool
+	}{
+		{name: "Root", input: "$", expected: NormalizedPath{}},
+		{name: "Names and indices", input: "$['paths']['/pets'][0][10]", expected: NormalizedPath{NameElement("paths"), NameElement("/pets"), IndexElement(0), IndexElement(10)}},
+		{name: "Empty name", input: "$['']", expected: NormalizedPath{NameElement("")}},
+		{name: "Escapes", input: `$['\b\f\n\r\t\'\\']`, expected: NormalizedPath{NameElement("\b\f\n\r\t'\\")}},
+		{name: "Unicode escapes", input: `$['\u0000\u0007\u000b\u000e\u001f']`, expected: NormalizedPath{NameElement("\x00\x07\x0b\x0e\x1f")}},
+		{name: "Unescaped characters", input: `$['"/ü☺']`, expected: NormalizedPath{NameElement(`"/ü☺`)}},
+		{name: "Missing root", input: "['a']", invalid: true},
+		{name: "Dot notation", input: "$.a", invalid: true},
+		{name: "Double quotes", input: `$["a"]`, invalid: true},
+		{name: "Multiple selectors", input: "$['a','b']", invalid: true},
+		{name: "Wildcard", input: "$[*]", invalid: true},
+		{name: "Negative index", input: "$[-1]", invalid: true},
+		{name: "Leading zero", input: "$[01]", invalid: true},
+		{name: "Whitespace", input: "$[ 'a' ]", invalid: true},
+		{name: "Unterminated name", input: "$['a]", invalid: true},
+		{name: "Unterminated segment", input: "$['a'", invalid: true},
+		{name: "Raw control character", input: "$['a\nb']", invalid: true},
+		{name: "Non normal escape", input: `$['\/']`, invalid: true},
+		{name: "Escaped double quote", input: `$['\"']`, invalid: true},
+		{name: "Unicode escape with short form", input: `$['\u000a']`, invalid: true},
+		{name: "Unicode escape of printable character", input: `$['\u0041']`, invalid: true},
+		{name: "Uppercase unicode escape", input: `$['\u001F']`, invalid: true},
+		{name: "Index out of range", input: "$[99999999999999999999]", invalid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := ParseNormalizedPath(test.input)
+			if test.invalid {
+				if err == nil {
+					t.Errorf("Expected an error, got %v", path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(path, test.expected) {
+				t.Errorf("Expected %#v, got %#v", test.expected, path)
+			}
+			if path.String() != test.input {
+				t.Errorf("Expected %s to round trip, got %s", test.input, path.String())
+			}
+		})
+	}
+}
+
+func TestNormalizedPathRoundTrip(t *testing.T) {
+	var root yaml.Node
+	err := yaml.Unmarshal([]byte(`
+paths:
+  "/pets/{id}":
+    get:
+      tags: [a, b]
+  "it's \"quoted\"\n\t\u0001": [x]
+`), &root)
+	if err != nil {
+		t.Fatalf("Error parsing YAML: %v", err)
+	}
+	path, err := NewPath("$..*")
+	if err != nil {
+		t.Fatalf("Error parsing JSON Path: %v", err)
+	}
+
+	for _, match := range path.QueryWithPaths(&root) {
+		text, err := match.Path.MarshalText()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var parsed NormalizedPath
+		if err := parsed.UnmarshalText(text); err != nil {
+			t.Fatalf("Failed to parse %s: %v", text, err)
+		}
+		if !reflect.DeepEqual(parsed, match.Path) {
+			t.Errorf("Expected %s to round trip", text)
+		}
+		if parsed.Resolve(&root) != match.Node {
+			t.Errorf("Expected %s to resolve to the matched node", text)
+		}
+		// normalized paths are valid queries selecting exactly the same node
+		query, err := NewPath(string(text))
+		if err != nil {
+			t.Fatalf("Failed to parse %s as a query: %v", text, err)
+		}
+		if result := query.Query(&root); len(result) != 1 || result[0] != match.Node {
+			t.Errorf("Expected %s to select the matched node", text)
+		}
+	}
+}
+
+func TestNormalizedPathInvalidUTF8(t *testing.T) {
+	// a Go map key can be any string, but a normalized path can only hold Unicode
+	root := NewValueNode(map[string]any{"a\xffb": 1})
+	path, err := NewPath("$.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []NormalizedPath
+	for p := range path.AllNodes(root) {
+		paths = append(paths, p)
+	}
+	if len(paths) != 1 {
+		t.Fatalf("Expected a single match, got %d", len(paths))
+	}
+	if _, err := paths[0].MarshalText(); err == nil || !strings.Contains(err.Error(), "not valid UTF-8") {
+		t.Errorf("Expected an error saying the name is not valid UTF-8, got %v", err)
+	}
+	// String replaces the invalid byte, and still reads back
+	parsed, err := ParseNormalizedPath(paths[0].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (NormalizedPath{NameElement("a\uFFFDb")}); !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("Expected %v, got %v", expected, parsed)
+	}
+}
+
+func TestNormalizedPathResolve(t *testing.T) {
+	root := yamlNodeFromString(`{"a": [1, {"b": 2}], "c": 3}`)
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "$", expected: `{"a": [1, {"b": 2}], "c": 3}`},
+		{path: "$['a'][1]['b']", expected: "2"},
+		{path: "$['c']", expected: "3"},
+		{path: "$['a'][2]", expected: ""},
+		{path: "$['c'][0]", expected: ""},
+		{path: "$[0]", expected: ""},
+		{path: "$['missing']", expected: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			path, err := ParseNormalizedPath(test.path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			node := path.Resolve(root)
+			if test.expected == "" {
+				if node != nil {
+					t.Errorf("Expected no node, got %s", nodeToString(node))
+				}
+				return
+			}
+			if node == nil || nodeToString(node) != test.expected {
+				t.Errorf("Expected %s, got %v", test.expected, node)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
 	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/token"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -115,13 +116,15 @@ func (p *JSONPath) parseSegment() (*segment, error) {
 func (p *JSONPath) parseInnerSegment() (retValue *innerSegment, err error) {
 	defer func() {
 		if p.mode[len(p.mode)-1] == modeSingular && retValue != nil {
+			// the segment may have been the last token
+			last := &p.tokens[min(p.current, len(p.tokens)-1)]
 			if len(retValue.selectors) > 1 {
 				retValue = nil
-				err = p.parseFailure(&p.tokens[p.current], "unexpected multiple selectors in singular query")
+				err = p.parseFailure(last, "unexpected multiple selectors in singular query")
 				return
 			} else if retValue.kind == segmentDotWildcard {
 				retValue = nil
-				err = p.parseFailure(&p.tokens[p.current], "unexpected wildcard in singular query")
+				err = p.parseFailure(last, "unexpected wildcard in singular query")
 				return
 			}
 		}
@@ -381,16 +384,16 @@ func (p *JSONPath) parseBasicExpr() (*basicExpr, error) {
 	switch p.tokens[p.current].Token {
 	case token.NOT:
 		p.current++
-		expr, err := p.parseLogicalOrExpr()
+		// "!" binds tighter than "&&" and "||", so it negates the next basic-expr only
+		negated, err := p.parseBasicExpr()
 		if err != nil {
 			return nil, err
 		}
-		// Inspect if the expr is topped by a parenExpr -- if so we can simplify
-		if len(expr.expressions) == 1 && len(expr.expressions[0].expressions) == 1 && expr.expressions[0].expressions[0].parenExpr != nil {
-			child := expr.expressions[0].expressions[0].parenExpr
-			child.not = !child.not
-			return &basicExpr{parenExpr: child}, nil
+		if negated.parenExpr != nil {
+			negated.parenExpr.not = !negated.parenExpr.not
+			return negated, nil
 		}
+		expr := &logicalOrExpr{expressions: []*logicalAndExpr{{expressions: []*basicExpr{negated}}}}
 		return &basicExpr{parenExpr: &parenExpr{not: true, expr: expr}}, nil
 	case token.PAREN_LEFT:
 		p.current++
@@ -550,8 +553,6 @@ func (p *JSONPath) parseTestExpr() (*testExpr, error) {
 		}
 		return &testExpr{functionExpr: funcExpr, not: not}, nil
 	}
-
-	return nil, p.parseFailure(&p.tokens[p.current], "unexpected token when parsing test expression")
 }
 
 func (p *JSONPath) parseFunctionExpr() (*functionExpr, error) {
@@ -629,6 +630,22 @@ func (p *JSONPath) parseSingleQuery() (*jsonPathAST, error) {
 	return &query, nil
 }
 
+// parseSingular parses the query again as a singular query, applying the checks
+// parseSegment does in modeSingular: it fails on descendant segments, and on segments with
+// anything but a single name, index or filter selector.
+func (p *JSONPath) parseSingular() (*jsonPathAST, error) {
+	singular := &JSONPath{tokenizer: p.tokenizer, tokens: p.tokens, current: 1, mode: []mode{modeSingular}, config: p.config}
+	var query jsonPathAST
+	for singular.current < len(singular.tokens) {
+		segment, err := singular.parseSegment()
+		if err != nil {
+			return nil, err
+		}
+		query.segments = append(query.segments, segment)
+	}
+	return &query, nil
+}
+
 func (p *JSONPath) parseFunctionArgument(single bool) (*functionArgument, error) {
 	//function-argument   = literal /
 	//	filter-query / ; (includes singular-query)
@@ -685,11 +702,12 @@ func (p *JSONPath) parseLiteral() (*literal, error) {
 	case token.INTEGER:
 		lit := p.tokens[p.current].Literal
 		p.current++
-		i, err := strconv.Atoi(lit)
-		if err != nil {
+		i, ok := new(big.Int).SetString(lit, 10)
+		if !ok {
 			return nil, p.parseFailure(&p.tokens[p.current], "expected integer")
 		}
-		return &literal{integer: &i}, nil
+		res := bigIntToLiteral(i)
+		return &res, nil
 	case token.FLOAT:
 		lit := p.tokens[p.current].Literal
 		p.current++
@@ -57,6 +57,18 @@ func TestParser(t *testing.T) {
 			name:  "Function call",
 			input: "$.books[?(length(@) > 100)]",
 		},
+		{
+			name:  "Negated expression followed by another",
+			input: "$[?!(@.a) || @.b]",
+		},
+		{
+			name:  "Escaped characters",
+			input: `$['new\nline', 'tab\t', '\u0001', 'it\'s', 'back\\slash']`,
+		},
+		{
+			name:  "Escaped characters in a filter",
+			input: `$[?@.a == 'a\r\nb\f']`,
+		},
 		{
 			name:    "Invalid missing closing ]",
 			input:   "$.paths.['/pet'",
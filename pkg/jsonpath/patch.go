@@ -0,0 +1,566 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PatchOp is the kind of a JSON Patch operation.
+type PatchOp string
+
+const (
+	PatchAdd     PatchOp = "add"
+	PatchRemove  PatchOp = "remove"
+	PatchReplace PatchOp = "replace"
+	PatchMove    PatchOp = "move"
+	PatchCopy    PatchOp = "copy"
+	PatchTest    PatchOp = "test"
+)
+
+// Patch is a JSON Patch (RFC 6902): a sequence of operations, applied in turn. It
+// marshals to and from JSON as the RFC describes, and to and from YAML likewise.
+type Patch []PatchOperation
+
+// PatchOperation is a single operation of a Patch. Path and From are JSON Pointers
+// (RFC 6901).
+type PatchOperation struct {
+	Op   PatchOp `yaml:"op"`
+	Path string  `yaml:"path"`
+	From string  `yaml:"from,omitempty"`
+	// Value is the value of add, replace and test operations, and nil for the others.
+	Value *yaml.Node `yaml:"value,omitempty"`
+}
+
+// PatchEdit describes the operation Patch generates for each node a query selects.
+type PatchEdit struct {
+	// Op is one of PatchAdd, PatchRemove, PatchReplace, PatchMove and PatchCopy.
+	Op PatchOp
+	// Value is what add and replace operations put in place.
+	Value *yaml.Node
+	// Child, if set, makes an add operation add Value under each selected node, rather
+	// than at it: it is the name of the member to add to a selected mapping, or "-" to
+	// append to a selected sequence.
+	Child string
+	// To is the JSON Pointer move and copy operations put each selected node at. Unless it
+	// ends in "/-", appending to a sequence, the query must select a single node.
+	To string
+}
+
+// Patch returns a JSON Patch applying edit to each node the query selects within root,
+// with each node's Normalized Path as a JSON Pointer: the path of a replace, remove or
+// add operation, or the from of a move or copy. root itself is not changed.
+//
+// The operations are ordered so that applying the patch to root has the same effect as
+// applying edit to each node at once: nodes are removed, or added before, in reverse
+// document order, so that the pointers to the others stay valid, and nodes within a node
+// that is also removed or moved are left out. Nodes are moved in document order, so that
+// those appended to a sequence keep it, and the pointer each is moved from accounts for the
+// nodes moved before it. Keys selected with the "~" extension, and members only merged
+// into a mapping, have no JSON Pointer, and are an error, as is removing or moving the
+// root.
+func (p *JSONPath) Patch(root *yaml.Node, edit PatchEdit) (Patch, error) {
+	switch edit.Op {
+	case PatchAdd, PatchReplace:
+		if edit.Value == nil {
+			return nil, fmt.Errorf("jsonpath: the %s operation needs a value", edit.Op)
+		}
+	case PatchMove, PatchCopy:
+		if _, err := parsePointer(edit.To); err != nil {
+			return nil, err
+		}
+	case PatchRemove:
+	default:
+		return nil, fmt.Errorf("jsonpath: cannot generate %q operations", edit.Op)
+	}
+	slots, err := p.slots(root)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]patchTarget, 0, len(slots))
+	for _, s := range slots {
+		switch {
+		case s.propertyName:
+			return nil, fmt.Errorf("jsonpath: cannot patch the key %s~: a JSON Pointer can't refer to a key", s.path)
+		case s.index < 0:
+			return nil, fmt.Errorf("jsonpath: cannot patch %s, which is merged from another mapping", s.path)
+		}
+		targets = append(targets, patchTarget{path: s.loc.path(), position: s.loc.position(root)})
+	}
+	removes := edit.Op == PatchRemove || edit.Op == PatchMove
+	if removes {
+		targets = outermostTargets(targets)
+	}
+	if removes && len(targets) == 1 && len(targets[0].path) == 0 {
+		return nil, fmt.Errorf("jsonpath: cannot %s the root node", edit.Op)
+	}
+	if (edit.Op == PatchMove || edit.Op == PatchCopy) && len(targets) > 1 && !strings.HasSuffix(edit.To, "/-") {
+		return nil, fmt.Errorf("jsonpath: cannot %s %d nodes to %s", edit.Op, len(targets), edit.To)
+	}
+	switch {
+	case edit.Op == PatchMove:
+		// nodes appended to a sequence keep their order, so they are moved first to last
+		slices.SortStableFunc(targets, func(a, b patchTarget) int {
+			return slices.Compare(a.position, b.position)
+		})
+	case removes || edit.Op == PatchAdd && edit.Child == "":
+		// removing or inserting a node only shifts the nodes after it, so working from the
+		// last node to the first keeps the pointers to the others valid
+		slices.SortStableFunc(targets, func(a, b patchTarget) int {
+			return slices.Compare(b.position, a.position)
+		})
+	}
+	paths := make([]NormalizedPath, len(targets))
+	for i, target := range targets {
+		paths[i] = target.path
+	}
+	if edit.Op == PatchMove {
+		paths = movedPaths(targets)
+	}
+
+	patch := make(Patch, len(paths))
+	for i, path := range paths {
+		pointer := path.JSONPointer()
+		switch edit.Op {
+		case PatchMove, PatchCopy:
+			patch[i] = PatchOperation{Op: edit.Op, Path: edit.To, From: pointer}
+		case PatchAdd:
+			if edit.Child != "" {
+				pointer += "/" + escapePointerToken(edit.Child)
+			}
+			patch[i] = PatchOperation{Op: edit.Op, Path: pointer, Value: edit.Value}
+		default:
+			patch[i] = PatchOperation{Op: edit.Op, Path: pointer, Value: edit.Value}
+		}
+	}
+	return patch, nil
+}
+
+// patchTarget is a node a patch operation is generated for.
+type patchTarget struct {
+	path NormalizedPath
+	// position is the index within its parent's Content of the node and of each of its
+	// ancestors, from the root down, which orders nodes as they appear in the document.
+	position []int
+}
+
+// position returns the position of the node at loc within root, as patchTarget has it.
+func (loc *location) position(root *yaml.Node) []int {
+	position := make([]int, loc.depth)
+	for ; loc.parent != nil; loc = loc.parent {
+		// ancestors are never keys, which is all slot can fail for
+		s, _ := loc.slot(root)
+		position[loc.depth-1] = s.index
+	}
+	return position
+}
+
+// outermostTargets returns targets without those within another of them.
+func outermostTargets(targets []patchTarget) []patchTarget {
+	result := make([]patchTarget, 0, len(targets))
+	for _, target := range targets {
+		if !slices.ContainsFunc(targets, func(other patchTarget) bool {
+			return len(other.path) < len(target.path) && slices.Equal(other.path, target.path[:len(other.path)])
+		}) {
+			result = append(result, target)
+		}
+	}
+	return result
+}
+
+// movedPaths returns the path of each of targets, which are in document order and none
+// within another, once the nodes before it have been moved away: each index is lowered by
+// the number of earlier nodes removed from before it in the same sequence.
+func movedPaths(targets []patchTarget) []NormalizedPath {
+	paths := make([]NormalizedPath, len(targets))
+	for i, target := range targets {
+		paths[i] = slices.Clone(target.path)
+		for _, moved := range targets[:i] {
+			last := len(moved.path) - 1
+			if last < len(target.path) && moved.path[last].Kind == PathElementIndex &&
+				target.path[last].Kind == PathElementIndex && target.path[last].Index > moved.path[last].Index &&
+				slices.Equal(moved.path[:last], target.path[:last]) {
+				paths[i][last].Index--
+			}
+		}
+	}
+	return paths
+}
+
+// ApplyPatch applies patch to root, a document or any other node, in place. Only the nodes
+// the operations add, remove or replace change, so the comments and styles of the rest of
+// the document are kept. Values are copied into the document, so the patch can be applied
+// again. Pointers lead through mappings and sequences, but not through aliases.
+//
+// As RFC 6902 requires, applying the patch is atomic: if any operation fails, including a
+// test, an error is returned and root is left unchanged.
+func ApplyPatch(root *yaml.Node, patch Patch) error {
+	trial := copyNode(root)
+	for i, op := range patch {
+		if err := op.apply(trial); err != nil {
+			return fmt.Errorf("jsonpath: patch operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	for _, op := range patch {
+		if err := op.apply(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (op PatchOperation) apply(root *yaml.Node) error {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return err
+	}
+	switch op.Op {
+	case PatchAdd, PatchReplace, PatchTest:
+		if op.Value == nil {
+			return errors.New("the operation has no value")
+		}
+	}
+	switch op.Op {
+	case PatchAdd:
+		return addAt(root, path, copyNode(op.Value))
+	case PatchRemove:
+		_, err := removeAt(root, path)
+		return err
+	case PatchReplace:
+		if _, err := nodeAt(root, path); err != nil {
+			return err
+		}
+		return replaceAt(root, path, copyNode(op.Value))
+	case PatchMove, PatchCopy:
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return err
+		}
+		var value *yaml.Node
+		if op.Op == PatchCopy {
+			if value, err = nodeAt(root, from); err != nil {
+				return err
+			}
+			value = copyNode(value)
+		} else {
+			if len(from) < len(path) && slices.Equal(from, path[:len(from)]) {
+				return fmt.Errorf("cannot move %s into itself", op.From)
+			}
+			if value, err = removeAt(root, from); err != nil {
+				return err
+			}
+		}
+		return addAt(root, path, value)
+	case PatchTest:
+		value, err := nodeAt(root, path)
+		if err != nil {
+			return err
+		}
+		if !equalsNode(NewYAMLNode(value), NewYAMLNode(op.Value)) {
+			return errors.New("test failed: the values differ")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// documentValue returns the node holding the value of root, the document's content if it
+// is a document node.
+func documentValue(root *yaml.Node) (*yaml.Node, error) {
+	if root.Kind != yaml.DocumentNode {
+		return root, nil
+	}
+	if len(root.Content) == 0 {
+		return nil, errors.New("the document is empty")
+	}
+	return root.Content[0], nil
+}
+
+// nodeAt returns the node at path within root.
+func nodeAt(root *yaml.Node, path []string) (*yaml.Node, error) {
+	n, err := documentValue(root)
+	for i, token := range path {
+		if err != nil {
+			return nil, err
+		}
+		var index int
+		if index, err = childIndex(n, token, pointerOf(path[:i])); err == nil {
+			n = n.Content[index]
+		}
+	}
+	return n, err
+}
+
+// childIndex returns the position within the Content of n of its member or element token,
+// which must exist; parent is the pointer of n, for errors.
+func childIndex(n *yaml.Node, token string, parent string) (int, error) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == token {
+				return i + 1, nil
+			}
+		}
+		return 0, fmt.Errorf("%s has no member %q", pointerOrRoot(parent), token)
+	case yaml.SequenceNode:
+		index, ok := pointerIndex(token)
+		if !ok || index >= len(n.Content) {
+			return 0, fmt.Errorf("%s has no element %q", pointerOrRoot(parent), token)
+		}
+		return index, nil
+	}
+	return 0, fmt.Errorf("%s is neither an object nor an array", pointerOrRoot(parent))
+}
+
+// addAt adds value at path within root: as a new member, or in place of an existing one,
+// of a mapping, or inserted into a sequence, before the element at the index or, for -,
+// at its end.
+func addAt(root *yaml.Node, path []string, value *yaml.Node) error {
+	if len(path) == 0 {
+		return replaceAt(root, path, value)
+	}
+	parent, err := nodeAt(root, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	token := path[len(path)-1]
+	switch parent.Kind {
+	case yaml.MappingNode:
+		if index, err := childIndex(parent, token, ""); err == nil {
+			parent.Content[index] = value
+			return nil
+		}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: token}
+		parent.Content = append(parent.Content, key, value)
+		return nil
+	case yaml.SequenceNode:
+		if token == "-" {
+			parent.Content = append(parent.Content, value)
+			return nil
+		}
+		index, ok := pointerIndex(token)
+		if !ok || index > len(parent.Content) {
+			return fmt.Errorf("%s has no index %q to add at", pointerOrRoot(pointerOf(path[:len(path)-1])), token)
+		}
+		parent.Content = slices.Insert(parent.Content, index, value)
+		return nil
+	}
+	return fmt.Errorf("%s is neither an object nor an array", pointerOrRoot(pointerOf(path[:len(path)-1])))
+}
+
+// removeAt removes the node at path within root, and returns it.
+func removeAt(root *yaml.Node, path []string) (*yaml.Node, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the root node")
+	}
+	parent, err := nodeAt(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	index, err := childIndex(parent, path[len(path)-1], pointerOf(path[:len(path)-1]))
+	if err != nil {
+		return nil, err
+	}
+	value := parent.Content[index]
+	if parent.Kind == yaml.MappingNode {
+		parent.Content = slices.Delete(parent.Content, index-1, index+1)
+	} else {
+		parent.Content = slices.Delete(parent.Content, index, index+1)
+	}
+	return value, nil
+}
+
+// replaceAt puts value in place of the node at path within root, which must exist.
+func replaceAt(root *yaml.Node, path []string, value *yaml.Node) error {
+	if len(path) > 0 {
+		parent, err := nodeAt(root, path[:len(path)-1])
+		if err != nil {
+			return err
+		}
+		index, err := childIndex(parent, path[len(path)-1], pointerOf(path[:len(path)-1]))
+		if err != nil {
+			return err
+		}
+		parent.Content[index] = value
+		return nil
+	}
+	switch {
+	case root.Kind != yaml.DocumentNode:
+		*root = *value
+	case len(root.Content) == 0:
+		root.Content = []*yaml.Node{value}
+	default:
+		root.Content[0] = value
+	}
+	return nil
+}
+
+func pointerOrRoot(pointer string) string {
+	if pointer == "" {
+		return "the root"
+	}
+	return pointer
+}
+
+// MarshalJSON encodes the operation as a JSON object, with its value converted to JSON:
+// mapping keys are kept in order, and scalars become the JSON value they resolve to.
+// Strings are written without escaping <, > and &, but json.Marshal escapes them again;
+// a json.Encoder with SetEscapeHTML(false) keeps them as they are.
+func (op PatchOperation) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteString(`{"op":`)
+	writeJSONString(&buf, string(op.Op))
+	buf.WriteString(`,"path":`)
+	writeJSONString(&buf, op.Path)
+	if op.From != "" || op.Op == PatchMove || op.Op == PatchCopy {
+		buf.WriteString(`,"from":`)
+		writeJSONString(&buf, op.From)
+	}
+	if op.Value != nil {
+		buf.WriteString(`,"value":`)
+		if err := writeNodeJSON(&buf, op.Value, map[*yaml.Node]bool{}); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes an operation from a JSON object. Its value is decoded into a node
+// without any style, so that it takes the style of the document it is added to.
+func (op *PatchOperation) UnmarshalJSON(data []byte) error {
+	var decoded struct {
+		Op    PatchOp         `json:"op"`
+		Path  *string         `json:"path"`
+		From  string          `json:"from"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if decoded.Path == nil {
+		return errors.New("jsonpath: patch operation has no path")
+	}
+	*op = PatchOperation{Op: decoded.Op, Path: *decoded.Path, From: decoded.From}
+	if decoded.Value == nil {
+		return nil
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(decoded.Value, &document); err != nil {
+		return err
+	}
+	op.Value = document.Content[0]
+	clearStyle(op.Value)
+	return nil
+}
+
+// UnmarshalYAML decodes an operation from a YAML mapping, keeping its value as it is.
+func (op *PatchOperation) UnmarshalYAML(node *yaml.Node) error {
+	var decoded struct {
+		Op    PatchOp   `yaml:"op"`
+		Path  *string   `yaml:"path"`
+		From  string    `yaml:"from"`
+		Value yaml.Node `yaml:"value"`
+	}
+	if err := node.Decode(&decoded); err != nil {
+		return err
+	}
+	if decoded.Path == nil {
+		return errors.New("jsonpath: patch operation has no path")
+	}
+	*op = PatchOperation{Op: decoded.Op, Path: *decoded.Path, From: decoded.From}
+	if decoded.Value.Kind != 0 {
+		op.Value = &decoded.Value
+	}
+	return nil
+}
+
+func clearStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		clearStyle(child)
+	}
+}
+
+// writeJSONString writes s as a JSON string, leaving <, > and & as they are rather than
+// escaping them for HTML, as descriptions often hold Markdown.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	// Encode ends the string with a newline
+	buf.Truncate(buf.Len() - 1)
+}
+
+// writeNodeJSON writes n as JSON. Aliases are written as the node they refer to, unless it
+// contains them, which JSON can't represent.
+func writeNodeJSON(buf *bytes.Buffer, n *yaml.Node, writing map[*yaml.Node]bool) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeNodeJSON(buf, n.Content[0], writing)
+	case yaml.AliasNode:
+		if writing[n.Alias] {
+			return fmt.Errorf("jsonpath: cannot write the recursive alias *%s as JSON", n.Value)
+		}
+		writing[n.Alias] = true
+		defer delete(writing, n.Alias)
+		return writeNodeJSON(buf, n.Alias, writing)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, n.Content[i].Value)
+			buf.WriteByte(':')
+			if err := writeNodeJSON(buf, n.Content[i+1], writing); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, child := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeNodeJSON(buf, child, writing); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+	node := NewYAMLNode(n)
+	switch node.Kind() {
+	case NodeNull:
+		buf.WriteString("null")
+	case NodeBool, NodeNumber:
+		switch value := node.Value().(type) {
+		case float64:
+			if math.IsInf(value, 0) || math.IsNaN(value) {
+				return fmt.Errorf("jsonpath: cannot write %s as JSON", n.Value)
+			}
+			buf.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+		case *big.Int:
+			buf.WriteString(value.String())
+		default:
+			fmt.Fprint(buf, value)
+		}
+	default:
+		writeJSONString(buf, n.Value)
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+	"strings"
+	"testing"
+)
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		document    string
+		edit        PatchEdit
+		value       string
+		patch       string
+		expected    string
+		errorSubstr string
+	}{
+		{
+			name:     "Replace",
+			input:    "$.paths[*]['x-internal']",
+			document: "{paths: {/pets: {x-internal: true}, /a~b: {x-internal: false}}}",
+			edit:     PatchEdit{Op: PatchReplace},
+			value:    "{since: 2}",
+			patch:    `[{"op":"replace","path":"/paths/~1pets/x-internal","value":{"since":2}},{"op":"replace","path":"/paths/~1a~0b/x-internal","value":{"since":2}}]`,
+			expected: "{paths: {/pets: {x-internal: {since: 2}}, /a~b: {x-internal: {since: 2}}}}",
+		},
+		{
+			name:     "Remove from last to first",
+			input:    "$.tags[0,2]",
+			document: "{tags: [a, b, c]}",
+			edit:     PatchEdit{Op: PatchRemove},
+			patch:    `[{"op":"remove","path":"/tags/2"},{"op":"remove","path":"/tags/0"}]`,
+			expected: "{tags: [b]}",
+		},
+		{
+			name:     "Remove leaves out nested nodes",
+			input:    "$..[?@.deprecated]",
+			document: "{a: {deprecated: true, b: {deprecated: true}}, c: 1}",
+			edit:     PatchEdit{Op: PatchRemove},
+			patch:    `[{"op":"remove","path":"/a"}]`,
+			expected: "{c: 1}",
+		},
+		{
+			name:     "Add before elements",
+			input:    "$.a[?@ > 1]",
+			document: "{a: [1, 2, 3]}",
+			edit:     PatchEdit{Op: PatchAdd},
+			value:    "0",
+			patch:    `[{"op":"add","path":"/a/2","value":0},{"op":"add","path":"/a/1","value":0}]`,
+			expected: "{a: [1, 0, 2, 0, 3]}",
+		},
+		{
+			name:     "Add a member",
+			input:    "$.paths.*",
+			document: "{paths: {/a: {}, /b: {get: 1}}}",
+			edit:     PatchEdit{Op: PatchAdd, Child: "x-tag"},
+			value:    "'1'",
+			patch:    `[{"op":"add","path":"/paths/~1a/x-tag","value":"1"},{"op":"add","path":"/paths/~1b/x-tag","value":"1"}]`,
+			expected: "{paths: {/a: {x-tag: '1'}, /b: {get: 1, x-tag: '1'}}}",
+		},
+		{
+			name:     "Append",
+			input:    "$.*.tags",
+			document: "{a: {tags: []}, b: {tags: [x]}}",
+			edit:     PatchEdit{Op: PatchAdd, Child: "-"},
+			value:    "y",
+			patch:    `[{"op":"add","path":"/a/tags/-","value":"y"},{"op":"add","path":"/b/tags/-","value":"y"}]`,
+			expected: "{a: {tags: [y]}, b: {tags: [x, y]}}",
+		},
+		{
+			name:     "Move",
+			input:    "$.a[?@ != 'y']",
+			document: "{a: [x, y, z], b: []}",
+			edit:     PatchEdit{Op: PatchMove, To: "/b/-"},
+			patch:    `[{"op":"move","path":"/b/-","from":"/a/0"},{"op":"move","path":"/b/-","from":"/a/1"}]`,
+			expected: "{a: [y], b: [x, z]}",
+		},
+		{
+			name:     "Move within the same sequence",
+			input:    "$.a[2,0]",
+			document: "{a: [w, x, y, z]}",
+			edit:     PatchEdit{Op: PatchMove, To: "/a/-"},
+			patch:    `[{"op":"move","path":"/a/-","from":"/a/0"},{"op":"move","path":"/a/-","from":"/a/1"}]`,
+			expected: "{a: [x, z, w, y]}",
+		},
+		{
+			name:     "Move from different sequences",
+			input:    "$..[?@ == 'x']",
+			document: "{a: [x, [y, x]], b: {c: x}, d: []}",
+			edit:     PatchEdit{Op: PatchMove, To: "/d/-"},
+			patch:    `[{"op":"move","path":"/d/-","from":"/a/0"},{"op":"move","path":"/d/-","from":"/a/0/1"},{"op":"move","path":"/d/-","from":"/b/c"}]`,
+			expected: "{a: [[y]], b: {}, d: [x, x, x]}",
+		},
+		{
+			name:     "Remove under different parents",
+			input:    "$[0, 1][0]",
+			document: "[[a, b], [c, d]]",
+			edit:     PatchEdit{Op: PatchRemove},
+			patch:    `[{"op":"remove","path":"/1/0"},{"op":"remove","path":"/0/0"}]`,
+			expected: "[[b], [d]]",
+		},
+		{
+			name:     "Remove a node and one within a later sibling",
+			input:    "$..[?@ == 'a']",
+			document: "[a, [a, b]]",
+			edit:     PatchEdit{Op: PatchRemove},
+			patch:    `[{"op":"remove","path":"/1/0"},{"op":"remove","path":"/0"}]`,
+			expected: "[[b]]",
+		},
+		{
+			name:     "Copy",
+			input:    "$.a",
+			document: "{a: [1]}",
+			edit:     PatchEdit{Op: PatchCopy, To: "/b"},
+			patch:    `[{"op":"copy","path":"/b","from":"/a"}]`,
+			expected: "{a: [1], b: [1]}",
+		},
+		{
+			name:     "Root",
+			input:    "$",
+			document: "{a: 1}",
+			edit:     PatchEdit{Op: PatchReplace},
+			value:    "2",
+			patch:    `[{"op":"replace","path":"","value":2}]`,
+			expected: "2",
+		},
+		{
+			name:     "No matches",
+			input:    "$.missing",
+			document: "{a: 1}",
+			edit:     PatchEdit{Op: PatchRemove},
+			patch:    `[]`,
+			expected: "{a: 1}",
+		},
+		{
+			name:        "Moving several nodes to one place",
+			input:       "$.a[*]",
+			document:    "{a: [1, 2]}",
+			edit:        PatchEdit{Op: PatchMove, To: "/b"},
+			errorSubstr: "cannot move 2 nodes to /b",
+		},
+		{
+			name:        "Copying several nodes to one place",
+			input:       "$.a[*]",
+			document:    "{a: [1, 2]}",
+			edit:        PatchEdit{Op: PatchCopy, To: "/b"},
+			errorSubstr: "cannot copy 2 nodes to /b",
+		},
+		{
+			name:     "Copying several nodes to the end of a sequence",
+			input:    "$.a[*]",
+			document: "{a: [1, 2], b: []}",
+			edit:     PatchEdit{Op: PatchCopy, To: "/b/-"},
+			patch:    `[{"op":"copy","path":"/b/-","from":"/a/0"},{"op":"copy","path":"/b/-","from":"/a/1"}]`,
+			expected: "{a: [1, 2], b: [1, 2]}",
+		},
+		{
+			name:        "Removing the root",
+			input:       "$",
+			document:    "{a: 1}",
+			edit:        PatchEdit{Op: PatchRemove},
+			errorSubstr: "cannot remove the root node",
+		},
+		{
+			name:     "HTML characters are not escaped",
+			input:    "$.a",
+			document: "{a: 1}",
+			edit:     PatchEdit{Op: PatchReplace},
+			value:    "'<b>Tom & Jerry</b>'",
+			patch:    `[{"op":"replace","path":"/a","value":"<b>Tom & Jerry</b>"}]`,
+			expected: "{a: '<b>Tom & Jerry</b>'}",
+		},
+		{
+			name:        "Keys",
+			input:       "$.a~",
+			document:    "{a: 1}",
+			edit:        PatchEdit{Op: PatchRemove},
+			errorSubstr: "a JSON Pointer can't refer to a key",
+		},
+		{
+			name:        "Missing value",
+			input:       "$.a",
+			document:    "{a: 1}",
+			edit:        PatchEdit{Op: PatchReplace},
+			errorSubstr: "needs a value",
+		},
+		{
+			name:        "Test",
+			input:       "$.a",
+			document:    "{a: 1}",
+			edit:        PatchEdit{Op: PatchTest},
+			errorSubstr: `cannot generate "test" operations`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := NewPath(test.input, config.WithPropertyNameExtension())
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			root := mutationDocument(t, test.document)
+			if test.value != "" {
+				test.edit.Value = mutationDocument(t, test.value).Content[0]
+			}
+			patch, err := path.Patch(root, test.edit)
+			if test.errorSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errorSubstr) {
+					t.Fatalf("Expected an error containing %q, got %v", test.errorSubstr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// json.Marshal escapes <, > and & in what MarshalJSON returns
+			buf := bytes.Buffer{}
+			encoder := json.NewEncoder(&buf)
+			encoder.SetEscapeHTML(false)
+			if err := encoder.Encode(patch); err != nil {
+				t.Fatal(err)
+			}
+			if encoded := strings.TrimSuffix(buf.String(), "\n"); encoded != test.patch {
+				t.Errorf("Expected patch:\n%s\nGot:\n%s", test.patch, encoded)
+			}
+			if actual := encodeDocument(t, mutationDocument(t, test.document)); actual != encodeDocument(t, root) {
+				t.Errorf("Generating the patch changed the document:\n%s", encodeDocument(t, root))
+			}
+			if err := ApplyPatch(root, patch); err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := encodeDocument(t, root), encodeDocument(t, mutationDocument(t, test.expected)); actual != expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+			}
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	const document = `# The API
+paths:
+  /pets: # all pets
+    get:
+      summary: "List pets"
+  /pets/{id}:
+    get: {summary: Get a pet}
+tags: [a, b]
+`
+	tests := []struct {
+		name        string
+		patch       string
+		expected    string
+		errorSubstr string
+	}{
+		{
+			name:  "Add and replace keep the rest",
+			patch: `[{"op":"add","path":"/paths/~1pets/post","value":{"summary":"Add a pet","tags":["pets"]}},{"op":"replace","path":"/tags/1","value":"c"}]`,
+			expected: `# The API
+paths:
+    /pets: # all pets
+        get:
+            summary: "List pets"
+        post:
+            summary: Add a pet
+            tags:
+                - pets
+    /pets/{id}:
+        get: {summary: Get a pet}
+tags: [a, c]`,
+		},
+		{
+			name:  "Remove, move and copy",
+			patch: `[{"op":"remove","path":"/tags/0"},{"op":"move","from":"/paths/~1pets~1{id}","path":"/pet"},{"op":"copy","from":"/tags","path":"/paths/~1pets/get/tags"}]`,
+			expected: `# The API
+paths:
+    /pets: # all pets
+        get:
+            summary: "List pets"
+            tags: [b]
+tags: [b]
+pet:
+    get: {summary: Get a pet}`,
+		},
+		{
+			name:  "Insert and append",
+			patch: `[{"op":"add","path":"/tags/0","value":"z"},{"op":"add","path":"/tags/-","value":"1"}]`,
+			expected: `# The API
+paths:
+    /pets: # all pets
+        get:
+            summary: "List pets"
+    /pets/{id}:
+        get: {summary: Get a pet}
+tags: [z, a, b, "1"]`,
+		},
+		{
+			name:     "Passing test",
+			patch:    `[{"op":"test","path":"/paths/~1pets~1{id}","value":{"get":{"summary":"Get a pet"}}},{"op":"replace","path":"","value":{}}]`,
+			expected: `{}`,
+		},
+		{
+			name:        "Failing test",
+			patch:       `[{"op":"remove","path":"/tags"},{"op":"test","path":"/paths/~1pets/get/summary","value":"List"}]`,
+			errorSubstr: "patch operation 1 (test /paths/~1pets/get/summary): test failed",
+		},
+		{
+			name:        "Missing member",
+			patch:       `[{"op":"replace","path":"/paths/~1dogs","value":1}]`,
+			errorSubstr: `/paths has no member "/dogs"`,
+		},
+		{
+			name:        "Index out of range",
+			patch:       `[{"op":"add","path":"/tags/3","value":1}]`,
+			errorSubstr: `/tags has no index "3" to add at`,
+		},
+		{
+			name:        "Leading zero",
+			patch:       `[{"op":"remove","path":"/tags/01"}]`,
+			errorSubstr: `/tags has no element "01"`,
+		},
+		{
+			name:        "Move into itself",
+			patch:       `[{"op":"move","from":"/paths","path":"/paths/x"}]`,
+			errorSubstr: "cannot move /paths into itself",
+		},
+		{
+			name:        "Remove the root",
+			patch:       `[{"op":"remove","path":""}]`,
+			errorSubstr: "cannot remove the root node",
+		},
+		{
+			name:        "Invalid pointer",
+			patch:       `[{"op":"remove","path":"/a~2"}]`,
+			errorSubstr: "~ must be followed by 0 or 1",
+		},
+		{
+			name:        "Scalar parent",
+			patch:       `[{"op":"add","path":"/tags/0/x","value":1}]`,
+			errorSubstr: "/tags/0 is neither an object nor an array",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var patch Patch
+			if err := json.Unmarshal([]byte(test.patch), &patch); err != nil {
+				t.Fatal(err)
+			}
+			root := mutationDocument(t, document)
+			err := ApplyPatch(root, patch)
+			if test.errorSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errorSubstr) {
+					t.Fatalf("Expected an error containing %q, got %v", test.errorSubstr, err)
+				}
+				if actual, expected := encodeDocument(t, root), encodeDocument(t, mutationDocument(t, document)); actual != expected {
+					t.Errorf("The document changed:\n%s", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := encodeDocument(t, root); actual != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPatchYAML(t *testing.T) {
+	patch := Patch{
+		{Op: PatchMove, From: "/a", Path: "/b"},
+		{Op: PatchAdd, Path: "/c", Value: &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "~"}},
+	}
+	encoded, err := json.Marshal(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `[{"op":"move","path":"/b","from":"/a"},{"op":"add","path":"/c","value":null}]`; string(encoded) != expected {
+		t.Errorf("Expected %s, got %s", expected, encoded)
+	}
+	var decoded Patch
+	if err := yaml.Unmarshal([]byte("- {op: move, from: /a, path: /b}\n- {op: add, path: /c, value: {d: [1]}}"), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if encoded, _ := json.Marshal(decoded); string(encoded) != `[{"op":"move","path":"/b","from":"/a"},{"op":"add","path":"/c","value":{"d":[1]}}]` {
+		t.Errorf("Unexpected patch decoded from YAML: %s", encoded)
+	}
+}
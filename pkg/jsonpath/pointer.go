@@ -0,0 +1,184 @@
+package jsonpath
+
+import (
+	"fmt"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// JSONPointer returns the path as a JSON Pointer (RFC 6901), e.g. /paths/~1pets/get for
+// $['paths']['/pets']['get'].
+func (p NormalizedPath) JSONPointer() string {
+	builder := strings.Builder{}
+	for _, element := range p {
+		builder.WriteByte('/')
+		if element.Kind == PathElementIndex {
+			builder.WriteString(strconv.Itoa(element.Index))
+		} else {
+			builder.WriteString(escapePointerToken(element.Name))
+		}
+	}
+	return builder.String()
+}
+
+// URIFragment returns the path as the URI fragment form of a JSON Pointer (RFC 6901 §6),
+// as used by $ref in OpenAPI and JSON Schema, e.g. #/components/schemas/Pet. Characters a
+// fragment can't hold are percent-encoded.
+func (p NormalizedPath) URIFragment() string {
+	return "#" + escapeFragment(p.JSONPointer())
+}
+
+// ResolvePointer returns the Normalized Path of the node a JSON Pointer, or its URI fragment
+// form, refers to within root. A pointer doesn't tell array indices from member names, so
+// each reference token is looked up in the document, and it is an error if there is no node
+// at the pointer.
+func ResolvePointer(root *yaml.Node, pointer string) (NormalizedPath, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+	loc := rootLocation(root, defaultConfig)
+	for i, token := range tokens {
+		var next *location
+		switch loc.node.Kind() {
+		case NodeObject:
+			next = member(loc, token)
+		case NodeArray:
+			if index, ok := pointerIndex(token); ok && index < loc.node.Len() {
+				next = elementLocation(loc, index)
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("jsonpath: %s has no node at %q", pointerOrRoot(pointerOf(tokens[:i])), token)
+		}
+		loc = next
+	}
+	return loc.path(), nil
+}
+
+// FromPointer returns the singular query selecting the node a JSON Pointer, or its URI
+// fragment form, refers to within root, so that e.g. the $ref #/components/schemas/Pet is
+// the query $['components']['schemas']['Pet']. A pointer doesn't tell array indices from
+// member names, so root decides: a reference token is an array index if it is looked up in
+// an array, and a member name otherwise, including where the pointer leads past the end of
+// root, or if root is nil. The query can then be used with SetCreate, which creates any
+// missing nodes on the way as mappings, as a JSON Patch add would.
+func FromPointer(root *yaml.Node, pointer string, opts ...config.Option) (*JSONPath, error) {
+	tokens, err := pointerTokens(pointer)
+	if err != nil {
+		return nil, err
+	}
+	path := make(NormalizedPath, len(tokens))
+	var loc *location
+	if root != nil {
+		loc = rootLocation(root, defaultConfig)
+	}
+	for i, token := range tokens {
+		index, isIndex := pointerIndex(token)
+		switch {
+		case loc != nil && loc.node.Kind() == NodeArray && isIndex:
+			path[i] = IndexElement(index)
+			if index < loc.node.Len() {
+				loc = elementLocation(loc, index)
+			} else {
+				loc = nil
+			}
+		case loc != nil:
+			path[i] = NameElement(token)
+			loc = member(loc, token)
+		default:
+			path[i] = NameElement(token)
+		}
+	}
+	return NewPath(path.String(), opts...)
+}
+
+// pointerTokens returns the reference tokens of a JSON Pointer, or of its URI fragment form,
+// which is percent-decoded first.
+func pointerTokens(pointer string) ([]string, error) {
+	if fragment, ok := strings.CutPrefix(pointer, "#"); ok {
+		decoded, err := url.PathUnescape(fragment)
+		if err != nil {
+			return nil, fmt.Errorf("jsonpath: invalid JSON Pointer fragment %q: %w", pointer, err)
+		}
+		pointer = decoded
+	}
+	return parsePointer(pointer)
+}
+
+// parsePointer returns the reference tokens of a JSON Pointer, unescaped.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("jsonpath: invalid JSON Pointer %q: it must be empty or start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || token[j+1] != '0' && token[j+1] != '1') {
+				return nil, fmt.Errorf("jsonpath: invalid JSON Pointer %q: ~ must be followed by 0 or 1", pointer)
+			}
+		}
+		tokens[i] = unescapePointerToken(token)
+	}
+	return tokens, nil
+}
+
+// pointerOf returns the JSON Pointer made of the given reference tokens.
+func pointerOf(tokens []string) string {
+	builder := strings.Builder{}
+	for _, token := range tokens {
+		builder.WriteByte('/')
+		builder.WriteString(escapePointerToken(token))
+	}
+	return builder.String()
+}
+
+// escapePointerToken escapes a member name as a JSON Pointer reference token.
+func escapePointerToken(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+// unescapePointerToken is the inverse of escapePointerToken. ~1 is replaced before ~0, so
+// that ~01 is ~1 and not /.
+func unescapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+// escapeFragment percent-encodes the bytes of s that a URI fragment (RFC 3986 §3.5) can't
+// hold as they are.
+func escapeFragment(s string) string {
+	const hex = "0123456789ABCDEF"
+	builder := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-._~!$&'()*+,;=:@/?", c) >= 0 {
+			builder.WriteByte(c)
+			continue
+		}
+		builder.WriteByte('%')
+		builder.WriteByte(hex[c>>4])
+		builder.WriteByte(hex[c&0xf])
+	}
+	return builder.String()
+}
+
+// pointerIndex returns the array index a reference token stands for, which must be a
+// decimal integer without leading zeros.
+func pointerIndex(token string) (int, bool) {
+	if token == "" || len(token) > 1 && token[0] == '0' {
+		return 0, false
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+	index, err := strconv.Atoi(token)
+	return index, err == nil
+}
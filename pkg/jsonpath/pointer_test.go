@@ -0,0 +1,161 @@
+package jsonpath
+
+import (
+	"gopkg.in/yaml.v3"
+	"strings"
+	"testing"
+)
+
+func TestJSONPointer(t *testing.T) {
+	tests := []struct {
+		path     NormalizedPath
+		pointer  string
+		fragment string
+	}{
+		{path: NormalizedPath{}, pointer: "", fragment: "#"},
+		{path: NormalizedPath{NameElement("components"), NameElement("schemas"), NameElement("Pet")}, pointer: "/components/schemas/Pet", fragment: "#/components/schemas/Pet"},
+		{path: NormalizedPath{NameElement("paths"), NameElement("/pets/{id}"), NameElement("get")}, pointer: "/paths/~1pets~1{id}/get", fragment: "#/paths/~1pets~1%7Bid%7D/get"},
+		{path: NormalizedPath{NameElement("a~b"), IndexElement(0), NameElement("")}, pointer: "/a~0b/0/", fragment: "#/a~0b/0/"},
+		{path: NormalizedPath{NameElement("100% é"), NameElement("#")}, pointer: "/100% é/#", fragment: "#/100%25%20%C3%A9/%23"},
+	}
+	for _, test := range tests {
+		t.Run(test.path.String(), func(t *testing.T) {
+			if actual := test.path.JSONPointer(); actual != test.pointer {
+				t.Errorf("Expected pointer %q, got %q", test.pointer, actual)
+			}
+			if actual := test.path.URIFragment(); actual != test.fragment {
+				t.Errorf("Expected fragment %q, got %q", test.fragment, actual)
+			}
+		})
+	}
+}
+
+func TestResolvePointer(t *testing.T) {
+	root := mutationDocument(t, `{paths: {'/pets/{id}': {get: {responses: {200: {description: OK}}}}}, tags: [a, {"~": b}], "100% é": 1}`)
+	tests := []struct {
+		pointer     string
+		expected    string
+		errorSubstr string
+	}{
+		{pointer: "", expected: "$"},
+		{pointer: "/paths/~1pets~1{id}/get/responses/200", expected: "$['paths']['/pets/{id}']['get']['responses']['200']"},
+		{pointer: "#/paths/~1pets~1%7Bid%7D/get", expected: "$['paths']['/pets/{id}']['get']"},
+		{pointer: "/tags/1/~0", expected: "$['tags'][1]['~']"},
+		{pointer: "#/100%25%20%C3%A9", expected: "$['100% é']"},
+		{pointer: "/tags/2", errorSubstr: `/tags has no node at "2"`},
+		{pointer: "/tags/01", errorSubstr: `/tags has no node at "01"`},
+		{pointer: "/tags/-", errorSubstr: `/tags has no node at "-"`},
+		{pointer: "/missing", errorSubstr: `the root has no node at "missing"`},
+		{pointer: "tags", errorSubstr: "it must be empty or start with /"},
+		{pointer: "/~2", errorSubstr: "~ must be followed by 0 or 1"},
+		{pointer: "#/%zz", errorSubstr: "invalid JSON Pointer fragment"},
+	}
+	for _, test := range tests {
+		t.Run(test.pointer, func(t *testing.T) {
+			path, err := ResolvePointer(root, test.pointer)
+			if test.errorSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errorSubstr) {
+					t.Fatalf("Expected an error containing %q, got %v", test.errorSubstr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path.String() != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, path)
+			}
+			if pointer := path.JSONPointer(); !strings.HasPrefix(test.pointer, "#") && pointer != test.pointer {
+				t.Errorf("Expected %s to convert back to %q, got %q", path, test.pointer, pointer)
+			}
+		})
+	}
+}
+
+func TestFromPointer(t *testing.T) {
+	const document = `{components: {schemas: {Pet: {type: object}}}, responses: {200: ok}, tags: [a, b], matrix: [[1]], "it's": {"a/b": 1}}`
+	tests := []struct {
+		pointer  string
+		query    string
+		expected string
+	}{
+		{pointer: "#/components/schemas/Pet", query: "$['components']['schemas']['Pet']", expected: "{type: object}"},
+		{pointer: "/responses/200", query: "$['responses']['200']", expected: "ok"},
+		{pointer: "/tags/1", query: "$['tags'][1]", expected: "b"},
+		{pointer: "/matrix/0/0", query: "$['matrix'][0][0]", expected: "1"},
+		{pointer: "/it's/a~1b", query: `$['it\'s']['a/b']`, expected: "1"},
+		{pointer: "/tags/01", query: "$['tags']['01']"},
+		{pointer: "/tags/2", query: "$['tags'][2]"},
+		{pointer: "/responses/404/0", query: "$['responses']['404']['0']"},
+		{pointer: "", query: "$", expected: document},
+	}
+	for _, test := range tests {
+		t.Run(test.pointer, func(t *testing.T) {
+			root := mutationDocument(t, document)
+			path, err := FromPointer(root, test.pointer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path.String() != test.query {
+				t.Errorf("Expected query %s, got %s", test.query, path)
+			}
+			var actual []string
+			for _, node := range path.Query(root) {
+				actual = append(actual, encodeDocument(t, node))
+			}
+			if strings.Join(actual, "\n") != test.expected {
+				t.Errorf("Expected %q, got %v", test.expected, actual)
+			}
+		})
+	}
+
+	// without a document, every reference token is a member name
+	path, err := FromPointer(nil, "/responses/200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path.String() != "$['responses']['200']" {
+		t.Errorf("Expected query $['responses']['200'], got %s", path)
+	}
+
+	if _, err := FromPointer(nil, "components/schemas"); err == nil {
+		t.Error("Expected an error for a pointer not starting with /")
+	}
+}
+
+func TestFromPointerSetCreate(t *testing.T) {
+	const document = `{responses: {200: ok}, tags: [a, b], matrix: [[1]]}`
+	tests := []struct {
+		pointer  string
+		expected string
+	}{
+		{pointer: "/responses/200", expected: "{responses: {200: x}, tags: [a, b], matrix: [[1]]}"},
+		{pointer: "/tags/1", expected: "{responses: {200: ok}, tags: [a, x], matrix: [[1]]}"},
+		{pointer: "/tags/2", expected: "{responses: {200: ok}, tags: [a, b, x], matrix: [[1]]}"},
+		{pointer: "/matrix/0/1", expected: "{responses: {200: ok}, tags: [a, b], matrix: [[1, x]]}"},
+		{pointer: "/responses/404/0", expected: `{responses: {200: ok, "404": {"0": x}}, tags: [a, b], matrix: [[1]]}`},
+		{pointer: "/tags/-"},
+	}
+	for _, test := range tests {
+		t.Run(test.pointer, func(t *testing.T) {
+			root := mutationDocument(t, document)
+			path, err := FromPointer(root, test.pointer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = path.SetCreate(root, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "x"})
+			if test.expected == "" {
+				if err == nil {
+					t.Errorf("Expected an error, got %s", encodeDocument(t, root))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := encodeDocument(t, root), encodeDocument(t, mutationDocument(t, test.expected)); actual != expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package jsonpath
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/yaml.v3"
+)
+
+// contextCheckInterval is how many nodes are visited between checks for cancellation.
+const contextCheckInterval = 256
+
+// QueryOption configures how QueryContext and QueryDocuments evaluate a query.
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	maxVisitedNodes int
+	maxResults      int
+	maxDepth        int
+	maxFilterDepth  int
+	// documentArray is set by WithDocumentArray
+	documentArray bool
+}
+
+// WithMaxVisitedNodes limits the number of nodes evaluation may visit, including nodes
+// visited while evaluating filters and descendant segments.
+func WithMaxVisitedNodes(n int) QueryOption {
+	return func(o *queryOptions) {
+		o.maxVisitedNodes = n
+	}
+}
+
+// WithMaxResults limits the number of nodes a query may select.
+func WithMaxResults(n int) QueryOption {
+	return func(o *queryOptions) {
+		o.maxResults = n
+	}
+}
+
+// WithMaxDepth limits how deep below the root descendant segments may recurse.
+func WithMaxDepth(n int) QueryOption {
+	return func(o *queryOptions) {
+		o.maxDepth = n
+	}
+}
+
+// WithMaxFilterDepth limits how deeply filter selectors may be nested while evaluating,
+// e.g. $[?@[?@[?@.a]]] has a filter depth of 3.
+func WithMaxFilterDepth(n int) QueryOption {
+	return func(o *queryOptions) {
+		o.maxFilterDepth = n
+	}
+}
+
+// Limit identifies one of the budgets enforced by QueryContext.
+type Limit int
+
+const (
+	LimitVisitedNodes Limit = iota
+	LimitResults
+	LimitDepth
+	LimitFilterDepth
+	// LimitAliasExpansions is set by config.WithMaxAliasExpansions
+	LimitAliasExpansions
+)
+
+func (l Limit) String() string {
+	switch l {
+	case LimitVisitedNodes:
+		return "visited nodes"
+	case LimitResults:
+		return "results"
+	case LimitDepth:
+		return "depth"
+	case LimitFilterDepth:
+		return "filter depth"
+	case LimitAliasExpansions:
+		return "alias expansions"
+	}
+	return "unknown"
+}
+
+// LimitError is returned by QueryContext when evaluation exceeds one of its limits.
+type LimitError struct {
+	Limit Limit
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("jsonpath: query exceeded the maximum %s (%d)", e.Limit, e.Max)
+}
+
+// QueryContext is like Query, but stops when ctx is cancelled or its deadline passes,
+// returning ctx.Err(), or when a limit set by opts is exceeded, returning a *LimitError.
+func (p *JSONPath) QueryContext(ctx context.Context, root *yaml.Node, opts ...QueryOption) ([]*yaml.Node, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ev := p.newYAMLEvaluation(root)
+	ev.ctx = ctx
+	for _, opt := range opts {
+		opt(&ev.options)
+	}
+
+	result := []*yaml.Node{}
+	p.ast.each(ev, func(loc *location) bool {
+		if !ev.result() {
+			return false
+		}
+		result = append(result, yamlNodeOf(loc.node))
+		return true
+	})
+	if ev.err != nil {
+		return nil, ev.err
+	}
+	return result, nil
+}
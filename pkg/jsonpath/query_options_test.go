@@ -0,0 +1,114 @@
+package jsonpath
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestQueryContextLimits(t *testing.T) {
+	root := yamlNodeFromString(`
+a:
+  b:
+    c:
+      d: [1, 2, 3]
+list: [{x: [{y: 1}]}, {x: [{y: 2}]}]
+`)
+	tests := []struct {
+		name     string
+		input    string
+		opts     []QueryOption
+		limit    Limit
+		expected int
+	}{
+		{name: "Unlimited", input: "$..*", expected: 16},
+		{name: "Visited nodes within budget", input: "$.a.b", opts: []QueryOption{WithMaxVisitedNodes(2)}, expected: 1},
+		{name: "Visited nodes exceeded", input: "$..*", opts: []QueryOption{WithMaxVisitedNodes(10)}, limit: LimitVisitedNodes},
+		{name: "Visited nodes include filters", input: "$.list[?count(@..*) > 100]", opts: []QueryOption{WithMaxVisitedNodes(5)}, limit: LimitVisitedNodes},
+		{name: "Results within budget", input: "$.a.b.c.d[*]", opts: []QueryOption{WithMaxResults(3)}, expected: 3},
+		{name: "Results exceeded", input: "$.a.b.c.d[*]", opts: []QueryOption{WithMaxResults(2)}, limit: LimitResults},
+		{name: "Depth within budget", input: "$..d", opts: []QueryOption{WithMaxDepth(5)}, expected: 1},
+		{name: "Depth exceeded", input: "$..d", opts: []QueryOption{WithMaxDepth(3)}, limit: LimitDepth},
+		{name: "Depth exceeded by chained descendants", input: "$..c..*", opts: []QueryOption{WithMaxDepth(3)}, limit: LimitDepth},
+		{name: "Filter depth within budget", input: "$.list[?@.x[?@.y == 2]]", opts: []QueryOption{WithMaxFilterDepth(2)}, expected: 1},
+		{name: "Filter depth exceeded", input: "$.list[?@.x[?@.y == 2]]", opts: []QueryOption{WithMaxFilterDepth(1)}, limit: LimitFilterDepth},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := NewPath(test.input)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			result, err := path.QueryContext(context.Background(), root, test.opts...)
+			if test.expected > 0 {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if len(result) != test.expected {
+					t.Errorf("Expected %d results, got %d", test.expected, len(result))
+				}
+				return
+			}
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("Expected a LimitError, got %v", err)
+			}
+			if limitErr.Limit != test.limit {
+				t.Errorf("Expected limit %s, got %s", test.limit, limitErr.Limit)
+			}
+			if result != nil {
+				t.Errorf("Expected no results, got %v", result)
+			}
+		})
+	}
+}
+
+// cancelAfter is a context that reports cancellation once Err has been called n times.
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	c.n--
+	if c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestQueryContextCancellation(t *testing.T) {
+	var root yaml.Node
+	err := yaml.Unmarshal([]byte("["+strings.Repeat("{a: [1, 2, 3]}, ", 1000)+"]"), &root)
+	if err != nil {
+		t.Fatalf("Error parsing YAML: %v", err)
+	}
+	path, err := NewPath("$..*")
+	if err != nil {
+		t.Fatalf("Error parsing JSON Path: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := path.QueryContext(ctx, &root); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	// cancelled part way through evaluation
+	ctx = &cancelAfter{Context: context.Background(), n: 2}
+	if _, err := path.QueryContext(ctx, &root); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	result, err := path.QueryContext(context.Background(), &root)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result) != 5000 {
+		t.Errorf("Expected 5000 results, got %d", len(result))
+	}
+}
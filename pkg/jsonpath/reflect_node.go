@@ -0,0 +1,400 @@
+package jsonpath
+
+import (
+	"cmp"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"math/big"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	jsonNumberType    = reflect.TypeFor[json.Number]()
+	bigIntType        = reflect.TypeFor[*big.Int]()
+)
+
+// reflectNode adapts an arbitrary Go value to Node using reflection, following the same
+// conventions as encoding/json: structs and maps are objects, slices and arrays are
+// arrays, nil pointers, slices and maps are null, and values implementing
+// encoding.TextMarshaler are strings.
+type reflectNode struct {
+	v  reflect.Value
+	id *valueID
+}
+
+// indirect follows pointers and interfaces until it reaches a value or a nil.
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		if v.Type().Implements(textMarshalerType) && v.Kind() == reflect.Pointer {
+			break
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func (n reflectNode) child(v reflect.Value, id valueID) reflectNode {
+	id.parent = n.id
+	return reflectNode{v: indirect(v), id: &id}
+}
+
+func (n reflectNode) isText() bool {
+	return n.v.Type().Implements(textMarshalerType) && n.v.CanInterface()
+}
+
+func (n reflectNode) Kind() NodeKind {
+	if !n.v.IsValid() {
+		return NodeNull
+	}
+	if n.v.Type() == jsonNumberType {
+		return NodeNumber
+	}
+	if n.v.Type() == bigIntType {
+		if n.v.IsNil() {
+			return NodeNull
+		}
+		return NodeNumber
+	}
+	if n.isText() {
+		if n.v.Kind() == reflect.Pointer && n.v.IsNil() {
+			return NodeNull
+		}
+		return NodeString
+	}
+	switch n.v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return NodeNull
+	case reflect.Bool:
+		return NodeBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return NodeNumber
+	case reflect.String:
+		return NodeString
+	case reflect.Slice:
+		if n.v.IsNil() {
+			return NodeNull
+		}
+		return NodeArray
+	case reflect.Array:
+		return NodeArray
+	case reflect.Map:
+		if n.v.IsNil() {
+			return NodeNull
+		}
+		return NodeObject
+	case reflect.Struct:
+		return NodeObject
+	}
+	return NodeOther
+}
+
+// Value returns the value of a scalar as its underlying Go type, so that e.g. a named
+// string type is a string. Anything else is returned as is.
+func (n reflectNode) Value() any {
+	switch n.Kind() {
+	case NodeNull:
+		return nil
+	case NodeBool:
+		return n.v.Bool()
+	case NodeString:
+		if n.isText() {
+			text, err := n.v.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return nil
+			}
+			return string(text)
+		}
+		return n.v.String()
+	case NodeNumber:
+		switch n.v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return n.v.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return n.v.Uint()
+		case reflect.Float32, reflect.Float64:
+			return n.v.Float()
+		case reflect.String:
+			return json.Number(n.v.String())
+		}
+	}
+	if n.v.CanInterface() {
+		return n.v.Interface()
+	}
+	return nil
+}
+
+func (n reflectNode) Len() int {
+	switch n.Kind() {
+	case NodeArray:
+		return n.v.Len()
+	case NodeObject:
+		if n.v.Kind() == reflect.Map {
+			return n.v.Len()
+		}
+		length := 0
+		for range n.Members() {
+			length++
+		}
+		return length
+	}
+	return 0
+}
+
+func (n reflectNode) Index(i int) Node {
+	return n.child(n.v.Index(i), valueID{index: i})
+}
+
+func (n reflectNode) Members() iter.Seq2[string, Node] {
+	return func(yield func(string, Node) bool) {
+		switch n.Kind() {
+		case NodeObject:
+			if n.v.Kind() == reflect.Map {
+				n.mapMembers(n.v, yield)
+				return
+			}
+			for _, field := range structFields(n.v.Type()).list {
+				value, ok := field.value(n.v)
+				if !ok {
+					continue
+				}
+				if field.inline {
+					if !n.mapMembers(value, yield) {
+						return
+					}
+					continue
+				}
+				if !yield(field.name, n.child(value, valueID{name: field.name})) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// mapMembers yields the members of a map in order of their names.
+func (n reflectNode) mapMembers(m reflect.Value, yield func(string, Node) bool) bool {
+	type member struct {
+		name  string
+		value reflect.Value
+	}
+	members := make([]member, 0, m.Len())
+	for iter := m.MapRange(); iter.Next(); {
+		members = append(members, member{mapKeyName(iter.Key()), iter.Value()})
+	}
+	slices.SortFunc(members, func(a, b member) int {
+		return cmp.Compare(a.name, b.name)
+	})
+	for _, member := range members {
+		if !yield(member.name, n.child(member.value, valueID{name: member.name})) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n reflectNode) Member(name string) (Node, bool) {
+	if n.Kind() != NodeObject {
+		return nil, false
+	}
+	if n.v.Kind() == reflect.Map {
+		return n.mapMember(n.v, name)
+	}
+	fields := structFields(n.v.Type())
+	if i, ok := fields.byName[name]; ok {
+		if value, ok := fields.list[i].value(n.v); ok {
+			return n.child(value, valueID{name: name}), true
+		}
+		return nil, false
+	}
+	for _, field := range fields.list {
+		if !field.inline {
+			continue
+		}
+		if value, ok := field.value(n.v); ok {
+			if found, ok := n.mapMember(value, name); ok {
+				return found, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (n reflectNode) mapMember(m reflect.Value, name string) (Node, bool) {
+	if m.Type().Key().Kind() == reflect.String {
+		value := m.MapIndex(reflect.ValueOf(name).Convert(m.Type().Key()))
+		if !value.IsValid() {
+			return nil, false
+		}
+		return n.child(value, valueID{name: name}), true
+	}
+	for iter := m.MapRange(); iter.Next(); {
+		if mapKeyName(iter.Key()) == name {
+			return n.child(iter.Value(), valueID{name: name}), true
+		}
+	}
+	return nil, false
+}
+
+func (n reflectNode) Identity() any {
+	return *n.id
+}
+
+// mapKeyName returns the member name a map key stands for.
+func mapKeyName(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	if marshaler, ok := key.Interface().(encoding.TextMarshaler); ok {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(key.Interface())
+}
+
+// structField is a member of a struct, as seen by reflectNode.
+type structField struct {
+	name  string
+	index []int
+	// omitEmpty is set by the omitempty tag option
+	omitEmpty bool
+	// yaml is set when the field was named by a yaml tag, which also omits zero structs
+	yaml bool
+	// inline is set for a map field tagged as inline, whose members are members of the struct
+	inline bool
+	// depth is the number of embedded structs the field is promoted through
+	depth  int
+	tagged bool
+}
+
+// value returns the value of the field within v, reporting false if the field is absent:
+// either it is omitted as empty, or it is promoted through a nil embedded pointer.
+func (f structField) value(v reflect.Value) (reflect.Value, bool) {
+	value, err := v.FieldByIndexErr(f.index)
+	if err != nil {
+		return reflect.Value{}, false
+	}
+	if f.inline {
+		value = indirect(value)
+		return value, value.Kind() == reflect.Map && value.Len() > 0
+	}
+	if f.omitEmpty && isEmptyValue(value, f.yaml) {
+		return reflect.Value{}, false
+	}
+	return value, true
+}
+
+func isEmptyValue(v reflect.Value, zeroStruct bool) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Struct:
+		return zeroStruct && v.IsZero()
+	}
+	return v.IsZero()
+}
+
+type fields struct {
+	list   []structField
+	byName map[string]int
+}
+
+var fieldCache sync.Map // map[reflect.Type]fields
+
+// structFields returns the members of a struct type: its exported fields and those
+// promoted from embedded or inline structs, named by their json tag, or else their yaml
+// tag, or else their Go name. As with encoding/json, when several fields have the same
+// name, the least deeply nested one wins, then the tagged one; otherwise none of them is
+// a member.
+func structFields(t reflect.Type) fields {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(fields)
+	}
+	var candidates []structField
+	var walk func(t reflect.Type, index []int, visited map[reflect.Type]bool)
+	walk = func(t reflect.Type, index []int, visited map[reflect.Type]bool) {
+		visited[t] = true
+		defer delete(visited, t)
+		for i := range t.NumField() {
+			f := t.Field(i)
+			fieldType := f.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if !f.IsExported() && !(f.Anonymous && fieldType.Kind() == reflect.Struct) {
+				continue
+			}
+			tag, isYAML := f.Tag.Get("json"), false
+			if tag == "" {
+				tag, isYAML = f.Tag.Get("yaml"), f.Tag.Get("yaml") != ""
+			}
+			if tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+			fieldIndex := append(slices.Clone(index), i)
+			inline := slices.Contains(strings.Split(options, ","), "inline")
+			if fieldType.Kind() == reflect.Struct && (inline || f.Anonymous && name == "") {
+				if !visited[fieldType] {
+					walk(fieldType, fieldIndex, visited)
+				}
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if inline && fieldType.Kind() == reflect.Map {
+				candidates = append(candidates, structField{index: fieldIndex, inline: true})
+				continue
+			}
+			field := structField{
+				name:      name,
+				index:     fieldIndex,
+				omitEmpty: slices.Contains(strings.Split(options, ","), "omitempty"),
+				yaml:      isYAML,
+				depth:     len(index),
+				tagged:    name != "",
+			}
+			if field.name == "" {
+				field.name = f.Name
+			}
+			candidates = append(candidates, field)
+		}
+	}
+	walk(t, nil, map[reflect.Type]bool{})
+
+	result := fields{byName: map[string]int{}}
+	for _, field := range candidates {
+		if field.inline {
+			result.list = append(result.list, field)
+			continue
+		}
+		if dominant(field, candidates) {
+			result.byName[field.name] = len(result.list)
+			result.list = append(result.list, field)
+		}
+	}
+	cached, _ := fieldCache.LoadOrStore(t, result)
+	return cached.(fields)
+}
+
+// dominant reports whether field wins over every other field with the same name.
+func dominant(field structField, candidates []structField) bool {
+	for _, other := range candidates {
+		if other.inline || other.name != field.name || slices.Equal(other.index, field.index) {
+			continue
+		}
+		if other.depth < field.depth || other.depth == field.depth && (other.tagged || !field.tagged) {
+			return false
+		}
+	}
+	return true
+}
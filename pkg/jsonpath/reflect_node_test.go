@@ -0,0 +1,171 @@
+package jsonpath
+
+import (
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testMethod string
+
+type testInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version,omitempty"`
+}
+
+type testBase struct {
+	ID     int    `json:"id"`
+	Hidden string `json:"-"`
+}
+
+type testOperation struct {
+	testBase
+	Method     testMethod     `yaml:"method"`
+	Summary    string         `yaml:"summary,omitempty"`
+	Tags       []string       `json:"tags"`
+	Extensions map[string]any `yaml:",inline"`
+	Info       *testInfo      `json:"info,omitempty"`
+	Created    time.Time      `json:"created"`
+	internal   string
+}
+
+type testNamed struct {
+	Name string
+}
+
+type testOtherNamed struct {
+	Name string
+}
+
+type testTaggedNamed struct {
+	Label string `json:"Name"`
+}
+
+func TestQueryValueReflect(t *testing.T) {
+	created := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	root := &[]testOperation{
+		{
+			testBase:   testBase{ID: 1, Hidden: "hidden"},
+			Method:     "GET",
+			Tags:       []string{"pets", "store"},
+			Extensions: map[string]any{"x-b": true, "x-a": 2},
+			Created:    created,
+			internal:   "internal",
+		},
+		{
+			testBase: testBase{ID: 2},
+			Method:   "POST",
+			Summary:  "Create",
+			Info:     &testInfo{Title: "T"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		root     any
+		expected []any
+	}{
+		{
+			name:     "Members in field order, with empty fields omitted and inline maps",
+			input:    "$[0].*~",
+			expected: []any{"id", "method", "tags", "x-a", "x-b", "created"},
+		},
+		{
+			name:     "Filter on a named string type",
+			input:    "$[?@.method == 'POST'].id",
+			expected: []any{int64(2)},
+		},
+		{
+			name:     "Filter through a pointer",
+			input:    "$[?@.info.title == 'T'].summary",
+			expected: []any{"Create"},
+		},
+		{
+			name:     "Inline map member",
+			input:    "$[?@['x-a'] == 2].id",
+			expected: []any{int64(1)},
+		},
+		{
+			name:     "Text marshaler",
+			input:    "$[0].created",
+			expected: []any{"2024-01-02T00:00:00Z"},
+		},
+		{
+			name:     "Nil slice is null",
+			input:    "$[?@.tags == null].id",
+			expected: []any{int64(2)},
+		},
+		{
+			name:     "Function over a slice",
+			input:    "$[?length(@.tags) == 2].method",
+			expected: []any{"GET"},
+		},
+		{
+			name:     "Ignored and unexported fields",
+			input:    "$[*]['Hidden','hidden','internal']",
+			expected: []any{},
+		},
+		{
+			name:  "Conflicting embedded fields",
+			input: "$.Name",
+			root: struct {
+				testNamed
+				testOtherNamed
+			}{testNamed{"a"}, testOtherNamed{"b"}},
+			expected: []any{},
+		},
+		{
+			name:  "Tagged embedded field wins",
+			input: "$.Name",
+			root: struct {
+				testNamed
+				testTaggedNamed
+			}{testNamed{"a"}, testTaggedNamed{"b"}},
+			expected: []any{"b"},
+		},
+		{
+			name:  "Shallower field wins",
+			input: "$.Name",
+			root: struct {
+				testNamed
+				Name string
+			}{testNamed{"a"}, "b"},
+			expected: []any{"b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := NewPath(test.input, config.WithPropertyNameExtension())
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			document := test.root
+			if document == nil {
+				document = root
+			}
+			actual := path.QueryValue(document)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("Expected:\n%#v\nGot:\n%#v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestQueryReflect(t *testing.T) {
+	root := &[]testOperation{{Method: "GET"}, {Method: "POST", Info: &testInfo{Title: "T"}}}
+	path, err := NewPath("$[*].info.title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := path.QueryReflect(root)
+	if len(values) != 1 || !values[0].CanSet() {
+		t.Fatalf("Expected a single settable value, got %v", values)
+	}
+	values[0].SetString("Updated")
+	if (*root)[1].Info.Title != "Updated" {
+		t.Errorf("Expected the title to be updated, got %q", (*root)[1].Info.Title)
+	}
+}
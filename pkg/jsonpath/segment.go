@@ -1,7 +1,6 @@
 package jsonpath
 
 import (
-	"gopkg.in/yaml.v3"
 	"strings"
 )
 
@@ -74,10 +73,20 @@ func (s innerSegment) ToString() string {
 	return builder.String()
 }
 
-func descend(value *yaml.Node, root *yaml.Node) []*yaml.Node {
-	result := []*yaml.Node{value}
-	for _, child := range value.Content {
-		result = append(result, descend(child, root)...)
+// descend yields value and then each of its descendants, in document order.
+func (ev *evaluation) descend(value *location, yield func(*location) bool) bool {
+	if ev.options.maxDepth > 0 && value.depth > ev.options.maxDepth {
+		ev.err = &LimitError{Limit: LimitDepth, Max: ev.options.maxDepth}
+		return false
 	}
-	return result
+	if ev.aliases && value.isCycle() {
+		// the node contains itself through an alias, so it has been descended into already
+		return true
+	}
+	if !yield(value) {
+		return false
+	}
+	return ev.children(value, func(child *location) bool {
+		return ev.descend(child, yield)
+	})
 }
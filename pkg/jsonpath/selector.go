@@ -33,7 +33,7 @@ type selector struct {
 func (s selector) ToString() string {
 	switch s.kind {
 	case selectorSubKindName:
-		return "'" + escapeString(s.name) + "'"
+		return "'" + escapeQuoted(s.name, '\'') + "'"
 	case selectorSubKindArrayIndex:
 		// int to string
 		return strconv.FormatInt(s.index, 10)
@@ -59,5 +59,4 @@ func (s selector) ToString() string {
 	default:
 		panic(fmt.Sprintf("unimplemented selector kind: %v", s.kind))
 	}
-	return ""
 }
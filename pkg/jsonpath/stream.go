@@ -0,0 +1,475 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+)
+
+// ErrNotStreamable is returned by StreamJSON for queries outside the subset it can
+// evaluate without loading the whole document.
+var ErrNotStreamable = errors.New("jsonpath: query cannot be evaluated over a stream")
+
+// StreamMatch is a value selected by StreamJSON, together with its Normalized Path.
+type StreamMatch struct {
+	Path NormalizedPath
+	// Value is the selected value, decoded as by json.Decoder with UseNumber: objects are
+	// map[string]any, arrays []any and numbers json.Number.
+	Value any
+}
+
+// StreamJSON evaluates the query against the JSON document read from r, without loading
+// the whole document. Only values the query selects are decoded, along with the elements
+// a filter selector has to be evaluated against; everything else is skipped token by token.
+//
+// Queries may use child and descendant segments with name, wildcard, non-negative index
+// and forward slice selectors, and filters that refer only to the current element. Other
+// queries yield an error wrapping ErrNotStreamable.
+//
+// Matches are yielded in document order, each at most once, as soon as they have been read.
+// A match is read as a whole before it is yielded, so matches nested inside it, as $..a
+// may select, follow it. Breaking out of the loop stops reading.
+func (p *JSONPath) StreamJSON(r io.Reader) iter.Seq2[StreamMatch, error] {
+	return func(yield func(StreamMatch, error) bool) {
+		if err := p.ast.streamable(); err != nil {
+			yield(StreamMatch{}, err)
+			return
+		}
+		decoder := json.NewDecoder(r)
+		decoder.UseNumber()
+		s := &streamer{decoder: decoder, segments: p.ast.segments, ev: newEvaluation(nil), yield: yield}
+		if err := s.value(nil, []int{0}, nil); err != nil && !s.stopped {
+			yield(StreamMatch{}, err)
+		}
+	}
+}
+
+// streamable reports why the query can't be evaluated by StreamJSON, if it can't.
+func (q jsonPathAST) streamable() error {
+	for _, segment := range q.segments {
+		var inner *innerSegment
+		switch segment.kind {
+		case segmentKindChild:
+			inner = segment.child
+		case segmentKindDescendant:
+			inner = segment.descendant
+		default:
+			return fmt.Errorf("%w: %s selects property names", ErrNotStreamable, segment.ToString())
+		}
+		if inner.kind != segmentLongHand {
+			continue
+		}
+		for _, selector := range inner.selectors {
+			switch selector.kind {
+			case selectorSubKindArrayIndex:
+				if selector.index < 0 {
+					return fmt.Errorf("%w: negative index %d needs the length of the array", ErrNotStreamable, selector.index)
+				}
+			case selectorSubKindArraySlice:
+				slice := selector.slice
+				if slice.start != nil && *slice.start < 0 || slice.end != nil && *slice.end < 0 || slice.step != nil && *slice.step <= 0 {
+					return fmt.Errorf("%w: slice %s needs the length of the array", ErrNotStreamable, selector.ToString())
+				}
+			case selectorSubKindFilter:
+				if selector.filter.expression.usesContext() {
+					return fmt.Errorf("%w: filter %s refers outside the current element, to the root ($) or a property name (~)", ErrNotStreamable, selector.ToString())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// streamer is the state of a single StreamJSON evaluation. Rather than a tree of nodes,
+// it tracks a set of states for the value being read: each is an index into segments,
+// meaning the segments from there on are still to be applied to the value.
+type streamer struct {
+	decoder  *json.Decoder
+	segments []*segment
+	ev       *evaluation
+	yield    func(StreamMatch, error) bool
+	stopped  bool
+}
+
+// pendingFilter is a filter selector a child must match to enter state next.
+type pendingFilter struct {
+	filter *filterSelector
+	next   int
+}
+
+// value reads the next value from the stream, found at path in the given states, and
+// with pending filters it must be decoded to evaluate.
+func (s *streamer) value(path NormalizedPath, states []int, filters []pendingFilter) error {
+	if len(filters) > 0 || slices.Contains(states, len(s.segments)) {
+		n, err := s.decode()
+		if err != nil {
+			return err
+		}
+		return s.evaluate(path, n, states, filters)
+	}
+	token, err := s.token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		for s.decoder.More() {
+			token, err := s.token()
+			if err != nil {
+				return err
+			}
+			name, _ := token.(string)
+			if err := s.child(path, NameElement(name), states); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for i := 0; s.decoder.More(); i++ {
+			if err := s.child(path, IndexElement(i), states); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+	// the closing delimiter
+	_, err = s.token()
+	return err
+}
+
+// child reads the member or element of the current value found under element.
+func (s *streamer) child(path NormalizedPath, element PathElement, states []int) error {
+	var childStates []int
+	var filters []pendingFilter
+	add := func(state int) {
+		if !slices.Contains(childStates, state) {
+			childStates = append(childStates, state)
+		}
+	}
+	for _, state := range states {
+		var inner *innerSegment
+		if s.segments[state].kind == segmentKindDescendant {
+			// keep looking for matches further down
+			add(state)
+			inner = s.segments[state].descendant
+		} else {
+			inner = s.segments[state].child
+		}
+		switch inner.kind {
+		case segmentDotWildcard:
+			add(state + 1)
+		case segmentDotMemberName:
+			if element.Kind == PathElementName && element.Name == inner.dotName {
+				add(state + 1)
+			}
+		case segmentLongHand:
+			for _, selector := range inner.selectors {
+				if selector.kind == selectorSubKindFilter {
+					filters = append(filters, pendingFilter{filter: selector.filter, next: state + 1})
+				} else if selector.streamSelects(element) {
+					add(state + 1)
+				}
+			}
+		}
+	}
+	path = append(path, element)
+	if len(childStates) == 0 && len(filters) == 0 {
+		return s.skip()
+	}
+	return s.value(path, childStates, filters)
+}
+
+// streamSelects reports whether a name, index, wildcard or slice selector selects the
+// child found under element.
+func (s selector) streamSelects(element PathElement) bool {
+	switch s.kind {
+	case selectorSubKindWildcard:
+		return true
+	case selectorSubKindName:
+		return element.Kind == PathElementName && element.Name == s.name
+	case selectorSubKindArrayIndex:
+		return element.Kind == PathElementIndex && int64(element.Index) == s.index
+	case selectorSubKindArraySlice:
+		if element.Kind != PathElementIndex {
+			return false
+		}
+		i := int64(element.Index)
+		start, step := int64(0), int64(1)
+		if s.slice.start != nil {
+			start = *s.slice.start
+		}
+		if s.slice.step != nil {
+			step = *s.slice.step
+		}
+		return i >= start && (s.slice.end == nil || i < *s.slice.end) && (i-start)%step == 0
+	}
+	return false
+}
+
+// token reads the next token. A stream that ends within a value is reported as
+// io.ErrUnexpectedEOF, as json.Decoder.Decode reports it, rather than as a syntax error.
+func (s *streamer) token() (json.Token, error) {
+	token, err := s.decoder.Token()
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.Error() == "unexpected end of JSON input" {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return token, err
+}
+
+// skip reads past the next value without decoding it.
+func (s *streamer) skip() error {
+	depth := 0
+	for {
+		token, err := s.token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// evaluate applies the remaining segments of each state to a value read into memory,
+// after resolving its pending filters, and yields the matches within it in document order.
+func (s *streamer) evaluate(path NormalizedPath, n *streamNode, states []int, filters []pendingFilter) error {
+	loc := pathLocation(path, n)
+	for _, pending := range filters {
+		if !slices.Contains(states, pending.next) && pending.filter.Matches(loc, s.ev) {
+			states = append(states, pending.next)
+		}
+	}
+	matches := map[*streamNode]NormalizedPath{}
+	for _, state := range states {
+		eachSegment(s.segments[state:], loc, s.ev, func(match *location) bool {
+			matches[match.node.(*streamNode)] = match.path()
+			return true
+		})
+	}
+	return s.yieldMatches(n, matches)
+}
+
+// yieldMatches yields the nodes within n that are in matches, n first and then the others
+// in the order they were read.
+func (s *streamer) yieldMatches(n *streamNode, matches map[*streamNode]NormalizedPath) error {
+	if path, ok := matches[n]; ok {
+		if !s.yield(StreamMatch{Path: path, Value: n.v}, nil) {
+			s.stopped = true
+			return errStopped
+		}
+		delete(matches, n)
+	}
+	for _, child := range n.children {
+		if len(matches) == 0 {
+			break
+		}
+		if err := s.yieldMatches(child, matches); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode reads the next value from the stream into memory.
+func (s *streamer) decode() (*streamNode, error) {
+	token, err := s.token()
+	if err != nil {
+		return nil, err
+	}
+	n := &streamNode{}
+	switch token {
+	case json.Delim('{'):
+		object := map[string]any{}
+		n.index = map[string]int{}
+		for s.decoder.More() {
+			token, err := s.token()
+			if err != nil {
+				return nil, err
+			}
+			name, _ := token.(string)
+			child, err := s.decode()
+			if err != nil {
+				return nil, err
+			}
+			if i, ok := n.index[name]; ok {
+				// as with encoding/json, a repeated name replaces the earlier member
+				n.children[i] = child
+			} else {
+				n.index[name] = len(n.children)
+				n.names = append(n.names, name)
+				n.children = append(n.children, child)
+			}
+			object[name] = child.v
+		}
+		n.v = object
+	case json.Delim('['):
+		array := []any{}
+		for s.decoder.More() {
+			child, err := s.decode()
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+			array = append(array, child.v)
+		}
+		n.v = array
+	default:
+		n.v = token
+		return n, nil
+	}
+	// the closing delimiter
+	_, err = s.token()
+	return n, err
+}
+
+// streamNode is a value read into memory by the streamer. Its value is decoded as
+// json.Decoder would, but unlike a map[string]any it keeps the members of an object in the
+// order they were read, so that matches within it are yielded in document order.
+type streamNode struct {
+	v any
+	// names are the member names of an object, in order; children are its members or
+	// the elements of an array
+	names    []string
+	children []*streamNode
+	// index is the position of each member of an object within names
+	index map[string]int
+}
+
+func (n *streamNode) Kind() NodeKind {
+	return valueNode{v: n.v}.Kind()
+}
+
+func (n *streamNode) Value() any {
+	return n.v
+}
+
+func (n *streamNode) Len() int {
+	return len(n.children)
+}
+
+func (n *streamNode) Index(i int) Node {
+	return n.children[i]
+}
+
+func (n *streamNode) Members() iter.Seq2[string, Node] {
+	return func(yield func(string, Node) bool) {
+		for i, name := range n.names {
+			if !yield(name, n.children[i]) {
+				return
+			}
+		}
+	}
+}
+
+func (n *streamNode) Member(name string) (Node, bool) {
+	if i, ok := n.index[name]; ok {
+		return n.children[i], true
+	}
+	return nil, false
+}
+
+func (n *streamNode) Identity() any {
+	return n
+}
+
+// errStopped unwinds the streamer once the consumer stops iterating.
+var errStopped = errors.New("jsonpath: stream stopped")
+
+// pathLocation returns a location for n found at path, whose ancestors are only known by
+// the path elements leading to them.
+func pathLocation(path NormalizedPath, n Node) *location {
+	loc := &location{}
+	for _, element := range path {
+		loc = &location{parent: loc, element: element, depth: loc.depth + 1}
+	}
+	loc.node = n
+	return loc
+}
+
+// usesContext reports whether a filter expression refers to anything but the element it
+// is evaluated against and its descendants: the root, or the element's name.
+func (e logicalOrExpr) usesContext() bool {
+	for _, expr := range e.expressions {
+		for _, basic := range expr.expressions {
+			if basic.usesContext() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e basicExpr) usesContext() bool {
+	switch {
+	case e.parenExpr != nil:
+		return e.parenExpr.expr.usesContext()
+	case e.comparisonExpr != nil:
+		return e.comparisonExpr.left.usesContext() || e.comparisonExpr.right.usesContext()
+	case e.testExpr != nil:
+		if e.testExpr.filterQuery != nil {
+			return e.testExpr.filterQuery.usesContext()
+		}
+		return e.testExpr.functionExpr.usesContext()
+	}
+	return false
+}
+
+func (c comparable) usesContext() bool {
+	switch {
+	case c.singularQuery != nil:
+		return c.singularQuery.absQuery != nil || c.singularQuery.relQuery.usesContext()
+	case c.functionExpr != nil:
+		return c.functionExpr.usesContext()
+	}
+	return false
+}
+
+func (e functionExpr) usesContext() bool {
+	for _, arg := range e.args {
+		switch {
+		case arg.filterQuery != nil && arg.filterQuery.usesContext(),
+			arg.logicalExpr != nil && arg.logicalExpr.usesContext(),
+			arg.functionExpr != nil && arg.functionExpr.usesContext():
+			return true
+		}
+	}
+	return false
+}
+
+func (q filterQuery) usesContext() bool {
+	if q.jsonPathQuery != nil {
+		return true
+	}
+	return q.relQuery.usesContext()
+}
+
+func (q relQuery) usesContext() bool {
+	for _, segment := range q.segments {
+		if segment.kind == segmentKindProperyName {
+			return true
+		}
+		inner := segment.child
+		if segment.kind == segmentKindDescendant {
+			inner = segment.descendant
+		}
+		if inner == nil {
+			continue
+		}
+		for _, selector := range inner.selectors {
+			if selector.kind == selectorSubKindFilter && selector.filter.expression.usesContext() {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStreamJSON(t *testing.T) {
+	const document = `{
+		"meta": {"count": 3, "id": "x"},
+		"items": [
+			{"id": 1, "tags": ["a"], "price": 5},
+			{"id": 2, "tags": [], "price": 15, "nested": {"id": 20}},
+			{"id": 3, "tags": ["b", "c"], "price": 25}
+		]
+	}`
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "Root",
+			input:    "$.meta",
+			expected: []string{`$['meta'] {"count":3,"id":"x"}`},
+		},
+		{
+			name:     "Names and indices",
+			input:    "$.items[1].id",
+			expected: []string{`$['items'][1]['id'] 2`},
+		},
+		{
+			name:     "Wildcard and slice",
+			input:    "$.items[0:3:2].tags[*]",
+			expected: []string{`$['items'][0]['tags'][0] "a"`, `$['items'][2]['tags'][0] "b"`, `$['items'][2]['tags'][1] "c"`},
+		},
+		{
+			name:     "Descendants in document order",
+			input:    "$..id",
+			expected: []string{`$['meta']['id'] "x"`, `$['items'][0]['id'] 1`, `$['items'][1]['id'] 2`, `$['items'][1]['nested']['id'] 20`, `$['items'][2]['id'] 3`},
+		},
+		{
+			name:     "Filter",
+			input:    "$.items[?@.price > 10 && length(@.tags) > 0].id",
+			expected: []string{`$['items'][2]['id'] 3`},
+		},
+		{
+			name:     "Descendant filter",
+			input:    "$..[?@.id == 20]",
+			expected: []string{`$['items'][1]['nested'] {"id":20}`},
+		},
+		{
+			name:     "Matches nested in a match",
+			input:    "$..[?@.id]",
+			expected: []string{`$['meta'] {"count":3,"id":"x"}`, `$['items'][0] {"id":1,"price":5,"tags":["a"]}`, `$['items'][1] {"id":2,"nested":{"id":20},"price":15,"tags":[]}`, `$['items'][1]['nested'] {"id":20}`, `$['items'][2] {"id":3,"price":25,"tags":["b","c"]}`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := NewPath(test.input)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			var actual []string
+			for match, err := range path.StreamJSON(strings.NewReader(document)) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				value, _ := json.Marshal(match.Value)
+				actual = append(actual, match.Path.String()+" "+string(value))
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("Expected:\n%v\nGot:\n%v", test.expected, actual)
+			}
+		})
+	}
+}
+
+// documentOrder returns the position of each node within n in document order, by its
+// Normalized Path, taking path as the path of n.
+func documentOrder(n *yaml.Node, path NormalizedPath, order map[string]int) {
+	order[path.String()] = len(order)
+	switch n.Kind {
+	case yaml.DocumentNode:
+		documentOrder(n.Content[0], path, order)
+	case yaml.MappingNode:
+		for i := 0; i < len(n.Content); i += 2 {
+			documentOrder(n.Content[i+1], append(slices.Clip(path), NameElement(n.Content[i].Value)), order)
+		}
+	case yaml.SequenceNode:
+		for i, child := range n.Content {
+			documentOrder(child, append(slices.Clip(path), IndexElement(i)), order)
+		}
+	}
+}
+
+func TestStreamJSONMatchesQuery(t *testing.T) {
+	tests := []struct {
+		input    string
+		document string
+	}{
+		{input: "$..a", document: `{"a": {"a": 1}}`},
+		{input: "$..a", document: `{"a": {"b": {"a": [{"a": 2}]}, "a": 3}, "c": {"a": 4}}`},
+		{input: "$..*", document: `{"a": {"a": 1, "b": [2, {"c": 3}]}, "d": [[4]]}`},
+		{input: "$..[?@.a]", document: `{"x": {"a": {"a": 1}}, "y": [{"a": 2, "b": {"a": 3}}]}`},
+		{input: "$..[0]..b", document: `[{"b": {"b": 1}}, [{"b": [{"b": 2}]}]]`},
+		{input: "$.a..*", document: `{"a": {"z": {"y": 1}, "b": 2}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.input+" "+test.document, func(t *testing.T) {
+			path, err := NewPath(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte(test.document), &root); err != nil {
+				t.Fatal(err)
+			}
+			order := map[string]int{}
+			documentOrder(&root, nil, order)
+			var expected []string
+			for _, match := range path.QueryWithPaths(&root) {
+				expected = append(expected, match.Path.String())
+			}
+			// a query may select a node more than once, where the stream yields it once
+			slices.SortFunc(expected, func(a, b string) int {
+				return order[a] - order[b]
+			})
+			expected = slices.Compact(expected)
+
+			var actual []string
+			for match, err := range path.StreamJSON(strings.NewReader(test.document)) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				actual = append(actual, match.Path.String())
+			}
+			if !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Expected the distinct matches of Query in document order:\n%q\nGot:\n%q", expected, actual)
+			}
+		})
+	}
+}
+
+// truncatedReader fails any read past the first n bytes.
+type truncatedReader struct {
+	data string
+	n    int
+}
+
+func (r *truncatedReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, errors.New("read past the end")
+	}
+	n := copy(p[:min(len(p), r.n)], r.data)
+	r.data = r.data[n:]
+	r.n -= n
+	return n, nil
+}
+
+func TestStreamJSONStopsEarly(t *testing.T) {
+	document := `[{"a": 1}, {"a": 2}` + strings.Repeat(" ", 4096) + `, {"a": 3}]`
+	path, err := NewPath("$[*].a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for match, err := range path.StreamJSON(&truncatedReader{data: document, n: 64}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match.Value != json.Number("1") {
+			t.Errorf("Expected 1, got %v", match.Value)
+		}
+		break
+	}
+}
+
+func TestStreamJSONErrors(t *testing.T) {
+	tests := []struct {
+		input       string
+		document    string
+		streamable  bool
+		errorSubstr string
+	}{
+		{input: "$[?@.a == $.b]", errorSubstr: "refers outside the current element"},
+		{input: "$[?count($..a) > 1]", errorSubstr: "refers outside the current element"},
+		{input: "$[?@[?@.a == $.b]]", errorSubstr: "refers outside the current element"},
+		{input: "$[-1]", errorSubstr: "negative index -1"},
+		{input: "$[::-1]", errorSubstr: "slice ::-1"},
+		{input: "$.a~", errorSubstr: "selects property names"},
+		{input: "$.*[?@~ == 'a']", errorSubstr: "refers outside the current element"},
+		{input: "$.*[?length(@~) > 1]", errorSubstr: "refers outside the current element"},
+		{input: "$.a", document: `{"a": [1,`, streamable: true, errorSubstr: "EOF"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			path, err := NewPath(test.input, config.WithPropertyNameExtension())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var streamErr error
+			for _, err := range path.StreamJSON(strings.NewReader(test.document)) {
+				if err != nil {
+					streamErr = err
+				}
+			}
+			if streamErr == nil || !strings.Contains(streamErr.Error(), test.errorSubstr) {
+				t.Fatalf("Expected an error containing %q, got %v", test.errorSubstr, streamErr)
+			}
+			if errors.Is(streamErr, ErrNotStreamable) == test.streamable {
+				t.Errorf("Unexpected ErrNotStreamable: %v", streamErr)
+			}
+			if test.streamable && !errors.Is(streamErr, io.ErrUnexpectedEOF) {
+				t.Errorf("Expected an unexpected EOF, got %v", streamErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package jsonpath
+
+import (
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/ast"
+	"math/big"
+)
+
+// AST returns the syntax tree of the query, for tools such as linters and editors to
+// inspect with ast.Walk. Each call returns a new copy of the tree.
+func (p *JSONPath) AST() *ast.Query {
+	return querySyntax(false, p.ast.segments)
+}
+
+func querySyntax(relative bool, segments []*segment) *ast.Query {
+	query := &ast.Query{Relative: relative, Segments: make([]*ast.Segment, len(segments))}
+	for i, s := range segments {
+		query.Segments[i] = s.syntax()
+	}
+	return query
+}
+
+func (s segment) syntax() *ast.Segment {
+	switch s.kind {
+	case segmentKindDescendant:
+		result := s.descendant.syntax()
+		result.Kind = ast.DescendantSegment
+		return result
+	case segmentKindProperyName:
+		return &ast.Segment{Kind: ast.PropertyNameSegment}
+	}
+	return s.child.syntax()
+}
+
+func (s innerSegment) syntax() *ast.Segment {
+	switch s.kind {
+	case segmentDotWildcard:
+		return &ast.Segment{Dot: true, Selectors: []ast.Selector{&ast.WildcardSelector{}}}
+	case segmentDotMemberName:
+		return &ast.Segment{Dot: true, Selectors: []ast.Selector{&ast.NameSelector{Name: s.dotName}}}
+	}
+	result := &ast.Segment{Selectors: make([]ast.Selector, len(s.selectors))}
+	for i, sel := range s.selectors {
+		result.Selectors[i] = sel.syntax()
+	}
+	return result
+}
+
+func (s selector) syntax() ast.Selector {
+	switch s.kind {
+	case selectorSubKindName:
+		return &ast.NameSelector{Name: s.name}
+	case selectorSubKindArrayIndex:
+		return &ast.IndexSelector{Index: s.index}
+	case selectorSubKindArraySlice:
+		return &ast.SliceSelector{Start: copyBound(s.slice.start), End: copyBound(s.slice.end), Step: copyBound(s.slice.step)}
+	case selectorSubKindFilter:
+		return &ast.FilterSelector{Expr: s.filter.expression.syntax()}
+	}
+	return &ast.WildcardSelector{}
+}
+
+func copyBound(bound *int64) *int64 {
+	if bound == nil {
+		return nil
+	}
+	value := *bound
+	return &value
+}
+
+// syntax returns the expression, without an OrExpr or AndExpr of a single operand.
+func (e logicalOrExpr) syntax() ast.Expr {
+	if len(e.expressions) == 1 {
+		return e.expressions[0].syntax()
+	}
+	result := &ast.OrExpr{Operands: make([]ast.Expr, len(e.expressions))}
+	for i, expr := range e.expressions {
+		result.Operands[i] = expr.syntax()
+	}
+	return result
+}
+
+func (e logicalAndExpr) syntax() ast.Expr {
+	if len(e.expressions) == 1 {
+		return e.expressions[0].syntax()
+	}
+	result := &ast.AndExpr{Operands: make([]ast.Expr, len(e.expressions))}
+	for i, expr := range e.expressions {
+		result.Operands[i] = expr.syntax()
+	}
+	return result
+}
+
+func (e basicExpr) syntax() ast.Expr {
+	switch {
+	case e.parenExpr != nil:
+		return &ast.ParenExpr{Not: e.parenExpr.not, Expr: e.parenExpr.expr.syntax()}
+	case e.comparisonExpr != nil:
+		return &ast.ComparisonExpr{
+			Left:  e.comparisonExpr.left.syntax(),
+			Op:    ast.ComparisonOp(e.comparisonExpr.op.ToString()),
+			Right: e.comparisonExpr.right.syntax(),
+		}
+	}
+	result := &ast.TestExpr{Not: e.testExpr.not}
+	if e.testExpr.filterQuery != nil {
+		result.Operand = e.testExpr.filterQuery.syntax()
+	} else {
+		result.Operand = e.testExpr.functionExpr.syntax()
+	}
+	return result
+}
+
+func (q filterQuery) syntax() *ast.Query {
+	if q.relQuery != nil {
+		return querySyntax(true, q.relQuery.segments)
+	}
+	return querySyntax(false, q.jsonPathQuery.segments)
+}
+
+func (c comparable) syntax() ast.Node {
+	switch {
+	case c.literal != nil:
+		return c.literal.syntax()
+	case c.singularQuery != nil && c.singularQuery.relQuery != nil:
+		return querySyntax(true, c.singularQuery.relQuery.segments)
+	case c.singularQuery != nil:
+		return querySyntax(false, c.singularQuery.absQuery.segments)
+	}
+	return c.functionExpr.syntax()
+}
+
+func (e functionExpr) syntax() *ast.FunctionCall {
+	result := &ast.FunctionCall{Name: e.funcType.String(), Args: make([]ast.Node, len(e.args))}
+	for i, arg := range e.args {
+		switch {
+		case arg.literal != nil:
+			result.Args[i] = arg.literal.syntax()
+		case arg.filterQuery != nil:
+			result.Args[i] = arg.filterQuery.syntax()
+		case arg.logicalExpr != nil:
+			result.Args[i] = arg.logicalExpr.syntax()
+		default:
+			result.Args[i] = arg.functionExpr.syntax()
+		}
+	}
+	return result
+}
+
+func (l literal) syntax() *ast.Literal {
+	switch {
+	case l.integer != nil:
+		return &ast.Literal{Value: int64(*l.integer)}
+	case l.float64 != nil:
+		return &ast.Literal{Value: *l.float64}
+	case l.bigInt != nil:
+		if l.bigInt.IsInt64() {
+			return &ast.Literal{Value: l.bigInt.Int64()}
+		}
+		return &ast.Literal{Value: new(big.Int).Set(l.bigInt)}
+	case l.string != nil:
+		return &ast.Literal{Value: *l.string}
+	case l.bool != nil:
+		return &ast.Literal{Value: *l.bool}
+	}
+	return &ast.Literal{}
+}
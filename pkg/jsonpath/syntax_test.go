@@ -0,0 +1,146 @@
+package jsonpath
+
+import (
+	"fmt"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/ast"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"reflect"
+	"testing"
+)
+
+// describeSyntax returns a short description of n, to compare walks by.
+func describeSyntax(n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.Query:
+		if n.Relative {
+			return "@"
+		}
+		return "$"
+	case *ast.Segment:
+		return fmt.Sprintf("segment %d dot=%v", n.Kind, n.Dot)
+	case *ast.NameSelector:
+		return "name " + n.Name
+	case *ast.WildcardSelector:
+		return "*"
+	case *ast.IndexSelector:
+		return fmt.Sprintf("index %d", n.Index)
+	case *ast.SliceSelector:
+		bound := func(b *int64) string {
+			if b == nil {
+				return "_"
+			}
+			return fmt.Sprint(*b)
+		}
+		return fmt.Sprintf("slice %s:%s:%s", bound(n.Start), bound(n.End), bound(n.Step))
+	case *ast.FilterSelector:
+		return "?"
+	case *ast.OrExpr:
+		return "||"
+	case *ast.AndExpr:
+		return "&&"
+	case *ast.ParenExpr:
+		return fmt.Sprintf("() not=%v", n.Not)
+	case *ast.ComparisonExpr:
+		return string(n.Op)
+	case *ast.TestExpr:
+		return fmt.Sprintf("test not=%v", n.Not)
+	case *ast.FunctionCall:
+		return n.Name + "()"
+	case *ast.Literal:
+		return fmt.Sprintf("literal %T %v", n.Value, n.Value)
+	}
+	return fmt.Sprintf("%T", n)
+}
+
+func TestAST(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			input:    "$.paths.*['get', 0]..[1:-1:2]~",
+			expected: []string{"$", "segment 0 dot=true", "name paths", "segment 0 dot=true", "*", "segment 0 dot=false", "name get", "index 0", "segment 1 dot=false", "slice 1:-1:2", "segment 2 dot=false"},
+		},
+		{
+			input: "$..[?@.price < 10 && !(@.sold || $.closed == true)]",
+			expected: []string{
+				"$", "segment 1 dot=false", "?", "&&",
+				"<", "@", "segment 0 dot=true", "name price", "literal int64 10",
+				"() not=true", "||",
+				"test not=false", "@", "segment 0 dot=true", "name sold",
+				"==", "$", "segment 0 dot=true", "name closed", "literal bool true",
+			},
+		},
+		{
+			input: "$[?length(@.tags) >= 2.5 || match(@.name, 'a.*') || count(@..y) == null || !search(@.x, 'b')]",
+			expected: []string{
+				"$", "segment 0 dot=false", "?", "||",
+				">=", "length()", "@", "segment 0 dot=true", "name tags", "literal float64 2.5",
+				"test not=false", "match()", "@", "segment 0 dot=true", "name name", "literal string a.*",
+				"==", "count()", "@", "segment 1 dot=true", "name y", "literal <nil> <nil>",
+				"() not=true", "test not=false", "search()", "@", "segment 0 dot=true", "name x", "literal string b",
+			},
+		},
+		{
+			input:    "$[?@.n == 123456789012345678901234567890]",
+			expected: []string{"$", "segment 0 dot=false", "?", "==", "@", "segment 0 dot=true", "name n", "literal *big.Int 123456789012345678901234567890"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			path, err := NewPath(test.input, config.WithPropertyNameExtension())
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			var actual []string
+			ast.Inspect(path.AST(), func(n ast.Node) bool {
+				if n != nil {
+					actual = append(actual, describeSyntax(n))
+				}
+				return true
+			})
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("Expected:\n%q\nGot:\n%q", test.expected, actual)
+			}
+		})
+	}
+}
+
+// countingVisitor counts the nodes it visits, without descending into filters.
+type countingVisitor struct {
+	visits *int
+	leaves *int
+}
+
+func (v countingVisitor) Visit(n ast.Node) ast.Visitor {
+	switch n.(type) {
+	case nil:
+		*v.leaves++
+		return nil
+	case *ast.FilterSelector:
+		*v.visits++
+		return nil
+	}
+	*v.visits++
+	return v
+}
+
+func TestASTWalk(t *testing.T) {
+	path, err := NewPath("$.a[?@.b][0]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var visits, leaves int
+	ast.Walk(countingVisitor{&visits, &leaves}, path.AST())
+	// $, .a, a, [?], ?, [0], 0; nil after the children of each visited node but the filter
+	if visits != 7 || leaves != 6 {
+		t.Errorf("Expected 7 visits and 6 ends, got %d and %d", visits, leaves)
+	}
+
+	tree := path.AST()
+	tree.Segments[0].Selectors[0].(*ast.NameSelector).Name = "changed"
+	if path.String() != "$.a[?@.b][0]" || path.AST().Segments[0].Selectors[0].(*ast.NameSelector).Name != "a" {
+		t.Errorf("Changing the tree changed the query: %s", path)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
 	"strconv"
 	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 // *****************************************************************************
@@ -579,7 +581,7 @@ illegal:
 			case 'n':
 				literal.WriteByte('\n')
 			case 'r':
-				literal.WriteByte('\n')
+				literal.WriteByte('\r')
 			case 't':
 				literal.WriteByte('\t')
 			case '\'':
@@ -598,6 +600,13 @@ illegal:
 				}
 			case '\\', '/':
 				literal.WriteByte(t.input[i])
+			case 'u':
+				r, size := scanUnicodeEscape(t.input[i+1:])
+				if size == 0 {
+					break illegal
+				}
+				literal.WriteRune(r)
+				i += size
 			default:
 				break illegal
 			}
@@ -610,6 +619,45 @@ illegal:
 	t.column = len(t.input) - 1
 }
 
+// scanUnicodeEscape decodes the hexchar following a "\\u" escape, returning the rune and
+// the number of bytes consumed, or 0 if the escape is invalid.
+//
+//	hexchar             = non-surrogate /
+//	                      (high-surrogate "\" %x75 low-surrogate)
+func scanUnicodeEscape(input string) (rune, int) {
+	r, ok := parseHex4(input)
+	if !ok {
+		return 0, 0
+	}
+	if !utf16.IsSurrogate(r) {
+		return r, 4
+	}
+	if r >= 0xDC00 || len(input) < 10 || input[4] != '\\' || input[5] != 'u' {
+		// lone or out of order surrogate
+		return 0, 0
+	}
+	low, ok := parseHex4(input[6:])
+	if !ok {
+		return 0, 0
+	}
+	decoded := utf16.DecodeRune(r, low)
+	if decoded == utf8.RuneError {
+		return 0, 0
+	}
+	return decoded, 10
+}
+
+func parseHex4(input string) (rune, bool) {
+	if len(input) < 4 {
+		return 0, false
+	}
+	i, err := strconv.ParseUint(input[:4], 16, 16)
+	if err != nil {
+		return 0, false
+	}
+	return rune(i), true
+}
+
 func (t *Tokenizer) scanNumber() {
 	start := t.pos
 	tokenType := INTEGER
@@ -610,12 +610,12 @@ func TestTokenizer_categorize(t *testing.T) {
 				if token.Token == ILLEGAL {
 					foundIllegal = true
 					if !tc.illegal {
-						t.Errorf(tokenizer.ErrorString(&token, "Illegal Token"))
+						t.Error(tokenizer.ErrorString(&token, "Illegal Token"))
 					}
 				}
 			}
 			if tc.illegal && !foundIllegal {
-				t.Errorf(tokenizer.ErrorTokenString(&tokenizedJsonPath[0], "Expected an illegal token"))
+				t.Error(tokenizer.ErrorTokenString(&tokenizedJsonPath[0], "Expected an illegal token"))
 			}
 
 			if tc.simple && foundIllegal {
@@ -632,12 +632,12 @@ func TestTokenizer_categorize(t *testing.T) {
 						}
 					}
 					if !simple {
-						t.Errorf(tokenizer.ErrorString(&token, "Expected a simple path, but found a non-simple token"))
+						t.Error(tokenizer.ErrorString(&token, "Expected a simple path, but found a non-simple token"))
 					}
 				}
 			}
 			if !tc.simple && tokenizedJsonPath.IsSimple() {
-				t.Errorf(tokenizer.ErrorTokenString(&tokenizedJsonPath[0], "Expected a non-simple path, but found it was simple"))
+				t.Error(tokenizer.ErrorTokenString(&tokenizedJsonPath[0], "Expected a non-simple path, but found it was simple"))
 			}
 		})
 	}
@@ -685,6 +685,41 @@ func TestString(t *testing.T) {
 			input: "\u0000",
 			err:   true,
 		},
+		{
+			name:     "Valid carriage return escape",
+			input:    `"a\rb"`,
+			expected: "a\rb",
+		},
+		{
+			name:     "Valid unicode escape",
+			input:    `'\u0001\u00e9\u263A'`,
+			expected: "\u0001\u00e9\u263a",
+		},
+		{
+			name:     "Valid surrogate pair escape",
+			input:    `"\uD834\uDD1E"`,
+			expected: "\U0001D11E",
+		},
+		{
+			name:  "Invalid short unicode escape",
+			input: `"\u12"`,
+			err:   true,
+		},
+		{
+			name:  "Invalid non-hex unicode escape",
+			input: `"\u12G4"`,
+			err:   true,
+		},
+		{
+			name:  "Invalid lone high surrogate",
+			input: `"\uD834"`,
+			err:   true,
+		},
+		{
+			name:  "Invalid lone low surrogate",
+			input: `"\uDD1E\uD834"`,
+			err:   true,
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,93 @@
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+)
+
+// Action is what Transform does with a node, as returned for it by its callback.
+type Action int
+
+const (
+	// ActionKeep leaves the node as it is.
+	ActionKeep Action = iota
+	// ActionReplace replaces the node with the one returned, as Set does. A node returned
+	// for more than one match is put in place once, and copied for the others.
+	ActionReplace
+	// ActionDelete removes the node from its parent, as Delete does.
+	ActionDelete
+	// ActionAbort stops the transform, without changing anything.
+	ActionAbort
+)
+
+// ErrTransformAborted is returned by Transform when its callback returns ActionAbort.
+var ErrTransformAborted = errors.New("jsonpath: transform aborted")
+
+// Transform calls transform with each node the query selects within root, once each and in
+// order, and then applies the actions it returns. It returns how many nodes it replaced or
+// deleted. If transform returns an error or ActionAbort, or an action can't be applied, it
+// changes nothing and returns the error, or ErrTransformAborted.
+//
+// The document is only changed once every node has been visited, so each Match describes
+// the document as the query found it, and the parents and indices of later matches stay
+// valid whatever is done with earlier ones. Nodes are replaced before any are deleted,
+// with sequence elements deleted from last to first.
+func (p *JSONPath) Transform(root *yaml.Node, transform func(m Match) (*yaml.Node, Action, error)) (int, error) {
+	slots, err := p.slots(root)
+	if err != nil {
+		return 0, err
+	}
+	var replaced, deleted []slot
+	var values []*yaml.Node
+	for _, s := range slots {
+		value, action, err := transform(s.loc.match())
+		if err != nil {
+			return 0, err
+		}
+		switch action {
+		case ActionKeep:
+		case ActionReplace:
+			if value == nil {
+				return 0, fmt.Errorf("jsonpath: cannot replace %s with a nil node", s.path)
+			}
+			if err := s.check(value); err != nil {
+				return 0, err
+			}
+			replaced = append(replaced, s)
+			values = append(values, value)
+		case ActionDelete:
+			deleted = append(deleted, s)
+		case ActionAbort:
+			return 0, ErrTransformAborted
+		default:
+			return 0, fmt.Errorf("jsonpath: unknown action %d for %s", action, s.path)
+		}
+	}
+	removals, err := removalsOf(deleted)
+	if err != nil {
+		return 0, err
+	}
+	// as with Set, no two places in the tree share a node
+	placed := map[*yaml.Node]bool{}
+	for i, value := range values {
+		if placed[value] {
+			values[i] = copyNode(value)
+		}
+		placed[value] = true
+	}
+	rootIndex := -1
+	for i, s := range replaced {
+		if s.parent == nil {
+			// the root is overwritten in place, so it is replaced last
+			rootIndex = i
+			continue
+		}
+		s.set(root, values[i])
+	}
+	removeAll(removals)
+	if rootIndex >= 0 {
+		replaced[rootIndex].set(root, values[rootIndex])
+	}
+	return len(replaced) + len(removals), nil
+}
@@ -0,0 +1,208 @@
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	const document = "{paths: {/a: {get: {operationId: GetA, deprecated: true}, post: {operationId: PostA}}, /b: {get: {operationId: GetB, description: Gets b}}}}"
+	errTransform := errors.New("transform failed")
+
+	tests := []struct {
+		name        string
+		input       string
+		transform   func(m Match) (*yaml.Node, Action, error)
+		expected    string
+		count       int
+		errorSubstr string
+	}{
+		{
+			name:  "Replace",
+			input: "$..operationId",
+			transform: func(m Match) (*yaml.Node, Action, error) {
+				return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: strings.ToLower(m.Node.Value)}, ActionReplace, nil
+			},
+			expected: "{paths: {/a: {get: {operationId: geta, deprecated: true}, post: {operationId: posta}}, /b: {get: {operationId: getb, description: Gets b}}}}",
+			count:    3,
+		},
+		{
+			name:  "Keep",
+			input: "$..operationId",
+			transform: func(m Match) (*yaml.Node, Action, error) {
+				if m.Node.Value == "GetB" {
+					return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "ListB"}, ActionReplace, nil
+				}
+				return nil, ActionKeep, nil
+			},
+			expected: "{paths: {/a: {get: {operationId: GetA, deprecated: true}, post: {operationId: PostA}}, /b: {get: {operationId: ListB, description: Gets b}}}}",
+			count:    1,
+		},
+		{
+			name:  "Delete with the parent context",
+			input: "$.paths.*.*",
+			transform: func(m Match) (*yaml.Node, Action, error) {
+				if m.Key == "post" || m.Path[1].Name == "/b" {
+					return nil, ActionDelete, nil
+				}
+				return nil, ActionKeep, nil
+			},
+			expected: "{paths: {/a: {get: {operationId: GetA, deprecated: true}}, /b: {}}}",
+			count:    2,
+		},
+		{
+			name:  "Replace and delete",
+			input: "$.paths['/a'].*[*]",
+			transform: func(m Match) (*yaml.Node, Action, error) {
+				if m.Key == "deprecated" {
+					return nil, ActionDelete, nil
+				}
+				return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: m.Key}, ActionReplace, nil
+			},
+			expected: "{paths: {/a: {get: {operationId: operationId}, post: {operationId: operationId}}, /b: {get: {operationId: GetB, description: Gets b}}}}",
+			count:    3,
+		},
+		{
+			name:  "Replace the root",
+			input: "$",
+			transform: func(m Match) (*yaml.Node, Action, error) {
+				return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "root"}, ActionReplace, nil
+			},
+			expected: "root",
+			count:    1,
+		},
+		{
+			name:  "Abort",
+			input: "$..operationId",
+			transform: func(m Match) (*yaml.Node, Action, error) {
+				if m.Node.Value == "GetB" {
+					return nil, ActionAbort, nil
+				}
+				return nil, ActionDelete, nil
+			},
+			expected:    document,
+			errorSubstr: ErrTransformAborted.Error(),
+		},
+		{
+			name:  "Error",
+			input: "$..operationId",
+			transform: func(m Match) (*yaml.Node, Action, error) {
+				if m.Node.Value == "GetB" {
+					return nil, ActionKeep, errTransform
+				}
+				return nil, ActionDelete, nil
+			},
+			expected:    document,
+			errorSubstr: errTransform.Error(),
+		},
+		{
+			name:  "Nil replacement",
+			input: "$..operationId",
+			transform: func(m Match) (*yaml.Node, Action, error) {
+				return nil, ActionReplace, nil
+			},
+			expected:    document,
+			errorSubstr: "nil node",
+		},
+		{
+			name:  "Deleting the root",
+			input: "$",
+			transform: func(m Match) (*yaml.Node, Action, error) {
+				return nil, ActionDelete, nil
+			},
+			expected:    document,
+			errorSubstr: "root",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := mutationDocument(t, document)
+			path, err := NewPath(test.input)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			count, err := path.Transform(root, test.transform)
+			if test.errorSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errorSubstr) {
+					t.Errorf("Expected an error containing %q, got %v", test.errorSubstr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if count != test.count {
+				t.Errorf("Expected %d nodes to be changed, got %d", test.count, count)
+			}
+			if actual := encodeDocument(t, root); actual != test.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTransformMatches(t *testing.T) {
+	root := mutationDocument(t, "{tags: [a, b, c], info: {title: T}}")
+	var visited []string
+	for _, input := range []string{"$.tags[-1,1,-2]", "$.info.*~"} {
+		path, err := NewPath(input, config.WithPropertyNameExtension())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = path.Transform(root, func(m Match) (*yaml.Node, Action, error) {
+			visited = append(visited, fmt.Sprintf("%s %s %q %d %v", m.Node.Value, m.Path, m.Key, m.Index, m.PropertyName))
+			return nil, ActionKeep, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// each node is visited once
+	expected := []string{`c $['tags'][2] "" 2 false`, `b $['tags'][1] "" 1 false`, `title $['info']['title'] "title" 0 true`}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Expected %v, got %v", expected, visited)
+	}
+}
+
+func TestTransformAborted(t *testing.T) {
+	root := mutationDocument(t, "{a: 1, b: 2}")
+	path, err := NewPath("$.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := path.Transform(root, func(m Match) (*yaml.Node, Action, error) {
+		return nil, ActionAbort, nil
+	})
+	if !errors.Is(err, ErrTransformAborted) {
+		t.Errorf("Expected ErrTransformAborted, got %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no nodes to be changed, got %d", count)
+	}
+}
+
+func TestTransformSharedReplacement(t *testing.T) {
+	root := mutationDocument(t, "{a: 1, b: 2}")
+	path, err := NewPath("$.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	_, err = path.Transform(root, func(m Match) (*yaml.Node, Action, error) {
+		return shared, ActionReplace, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// editing one replacement leaves the other alone
+	mapping := root.Content[0]
+	a := mapping.Content[1]
+	a.Content = append(a.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "x"}, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "1"})
+	if expected, actual := "{a: {x: 1}, b: {}}", encodeDocument(t, root); actual != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,133 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"math/big"
+	"reflect"
+	"slices"
+)
+
+// valueNode adapts a plain Go value to Node.
+type valueNode struct {
+	v  any
+	id *valueID
+}
+
+// valueID identifies a value by where it was found, as Go values mostly can't be compared
+// by reference. Two nodes are the same node when their parents are and they were found
+// under the same name or index.
+type valueID struct {
+	parent *valueID
+	name   string
+	index  int
+}
+
+// NewValueNode returns the Node for a Go value. The types produced by unmarshalling JSON or
+// YAML into an any are handled directly: map[string]any and map[any]any are objects and
+// []any is an array. Any other value, including a reflect.Value, is navigated using
+// reflection: struct fields are object members named by their json or yaml tag, omitempty
+// and inline fields are honored, and slices, arrays and maps are arrays and objects. Since
+// Go maps are unordered, their members are visited in order of their names.
+func NewValueNode(v any) Node {
+	return newValueNode(v, &valueID{})
+}
+
+func newValueNode(v any, id *valueID) Node {
+	switch v := v.(type) {
+	case nil, bool, string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr,
+		float32, float64, json.Number, *big.Int, []any, map[string]any, map[any]any:
+		return valueNode{v: v, id: id}
+	case reflect.Value:
+		return reflectNode{v: indirect(v), id: id}
+	}
+	return reflectNode{v: indirect(reflect.ValueOf(v)), id: id}
+}
+
+func (n valueNode) child(v any, id valueID) Node {
+	id.parent = n.id
+	return newValueNode(v, &id)
+}
+
+func (n valueNode) Kind() NodeKind {
+	switch n.v.(type) {
+	case nil:
+		return NodeNull
+	case bool:
+		return NodeBool
+	case string:
+		return NodeString
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64, json.Number, *big.Int:
+		return NodeNumber
+	case []any:
+		return NodeArray
+	case map[string]any, map[any]any:
+		return NodeObject
+	}
+	return NodeOther
+}
+
+func (n valueNode) Value() any {
+	return n.v
+}
+
+func (n valueNode) Len() int {
+	switch v := n.v.(type) {
+	case []any:
+		return len(v)
+	case map[string]any:
+		return len(v)
+	case map[any]any:
+		return len(v)
+	}
+	return 0
+}
+
+func (n valueNode) Index(i int) Node {
+	return n.child(n.v.([]any)[i], valueID{index: i})
+}
+
+func (n valueNode) Members() iter.Seq2[string, Node] {
+	return func(yield func(string, Node) bool) {
+		switch v := n.v.(type) {
+		case map[string]any:
+			for _, name := range slices.Sorted(maps.Keys(v)) {
+				if !yield(name, n.child(v[name], valueID{name: name})) {
+					return
+				}
+			}
+		case map[any]any:
+			names := make(map[string]any, len(v))
+			for k := range v {
+				names[fmt.Sprint(k)] = k
+			}
+			for _, name := range slices.Sorted(maps.Keys(names)) {
+				if !yield(name, n.child(v[names[name]], valueID{name: name})) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (n valueNode) Member(name string) (Node, bool) {
+	switch v := n.v.(type) {
+	case map[string]any:
+		if value, ok := v[name]; ok {
+			return n.child(value, valueID{name: name}), true
+		}
+	case map[any]any:
+		for k, value := range v {
+			if fmt.Sprint(k) == name {
+				return n.child(value, valueID{name: name}), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (n valueNode) Identity() any {
+	return *n.id
+}
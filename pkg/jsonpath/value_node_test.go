@@ -0,0 +1,133 @@
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"testing"
+)
+
+func TestQueryValue(t *testing.T) {
+	const document = `{
+		"store": {
+			"book": [
+				{"title": "Sayings", "price": 8.95, "tags": ["a", "b"]},
+				{"title": "Sword", "price": 12, "isbn": "0-553"},
+				{"title": "Moby", "price": 8.99, "isbn": "0-395"}
+			],
+			"bicycle": {"color": "red", "price": 399}
+		}
+	}`
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []any
+	}{
+		{
+			name:     "Member names",
+			input:    "$.store.bicycle.color",
+			expected: []any{"red"},
+		},
+		{
+			name:     "Wildcard visits members in name order",
+			input:    "$.store.bicycle.*",
+			expected: []any{"red", float64(399)},
+		},
+		{
+			name:     "Negative index",
+			input:    "$.store.book[-1].title",
+			expected: []any{"Moby"},
+		},
+		{
+			name:     "Slice",
+			input:    "$.store.book[::2].title",
+			expected: []any{"Sayings", "Moby"},
+		},
+		{
+			name:     "Filter comparing an integer literal to a float64",
+			input:    "$.store.book[?@.price == 12].title",
+			expected: []any{"Sword"},
+		},
+		{
+			name:     "Filter with existence test",
+			input:    "$.store.book[?@.isbn].title",
+			expected: []any{"Sword", "Moby"},
+		},
+		{
+			name:     "Filter with function",
+			input:    "$.store.book[?length(@.tags) == 2].title",
+			expected: []any{"Sayings"},
+		},
+		{
+			name:     "Filter comparing structured values",
+			input:    `$.store.book[?@.tags == $.store.book[0].tags].title`,
+			expected: []any{"Sayings"},
+		},
+		{
+			name:     "Descendants",
+			input:    "$..price",
+			expected: []any{float64(399), 8.95, float64(12), 8.99},
+		},
+		{
+			name:     "Descendants are unique",
+			input:    "$.store.book[0].tags..[0,0]",
+			expected: []any{"a"},
+		},
+		{
+			name:     "No match",
+			input:    "$.store.pen",
+			expected: []any{},
+		},
+	}
+
+	var root any
+	if err := json.Unmarshal([]byte(document), &root); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := NewPath(test.input)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			actual := path.QueryValue(root)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("Expected:\n%#v\nGot:\n%#v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestQueryValueTypes(t *testing.T) {
+	decoder := json.NewDecoder(bytes.NewBufferString(`{"a": [1, 2.5, 10000000000000000000]}`))
+	decoder.UseNumber()
+	var numbers any
+	if err := decoder.Decode(&numbers); err != nil {
+		t.Fatal(err)
+	}
+	path, err := NewPath("$.a[?@ > 2]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []any{json.Number("2.5"), json.Number("10000000000000000000")}
+	if actual := path.QueryValue(numbers); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, actual)
+	}
+
+	// YAML decodes mappings with non-string keys as map[any]any
+	var document any
+	if err := yaml.Unmarshal([]byte("1: one\ntwo: 2\n"), &document); err != nil {
+		t.Fatal(err)
+	}
+	path, err = NewPath("$[?@ == 'one' || @ == 2]~", config.WithPropertyNameExtension())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []any{"1", "two"}
+	if actual := path.QueryValue(document); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, actual)
+	}
+}
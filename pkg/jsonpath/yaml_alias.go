@@ -0,0 +1,143 @@
+package jsonpath
+
+import (
+	"gopkg.in/yaml.v3"
+	"iter"
+)
+
+// yamlAliasNode adapts a *yaml.Node like yamlNode, but resolves aliases to the nodes their
+// anchors refer to, and expands merge keys. Every alias resolved is accounted for by ev,
+// which stops the evaluation once it has resolved too many.
+type yamlAliasNode struct {
+	yamlNode
+	ev *evaluation
+}
+
+// resolveAliases returns the Node for n, following it if it is an alias. Following an
+// alias counts as an expansion, each time it is followed.
+func (ev *evaluation) resolveAliases(n *yaml.Node) Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		if !ev.expandAlias() {
+			break
+		}
+		n = n.Alias
+	}
+	return yamlAliasNode{yamlNode{n, ev.config}, ev}
+}
+
+// expandAlias accounts for one more alias being resolved. It returns false, recording
+// why, if the evaluation should stop.
+func (ev *evaluation) expandAlias() bool {
+	ev.aliasExpansions++
+	if ev.maxAliasExpansions > 0 && ev.aliasExpansions > ev.maxAliasExpansions {
+		if ev.err == nil {
+			ev.err = &LimitError{Limit: LimitAliasExpansions, Max: ev.maxAliasExpansions}
+		}
+		return false
+	}
+	return true
+}
+
+func (n yamlAliasNode) Len() int {
+	if n.node.Kind != yaml.MappingNode {
+		return n.yamlNode.Len()
+	}
+	// counting members doesn't resolve their values
+	names := map[string]bool{}
+	n.eachMember(n.node, map[*yaml.Node]bool{}, func(key, _ *yaml.Node) bool {
+		names[key.Value] = true
+		return true
+	})
+	return len(names)
+}
+
+func (n yamlAliasNode) Index(i int) Node {
+	return n.ev.resolveAliases(n.node.Content[i])
+}
+
+func (n yamlAliasNode) Members() iter.Seq2[string, Node] {
+	return func(yield func(string, Node) bool) {
+		seen := map[string]bool{}
+		n.eachMember(n.node, map[*yaml.Node]bool{}, func(key, value *yaml.Node) bool {
+			if seen[key.Value] {
+				return true
+			}
+			seen[key.Value] = true
+			return yield(key.Value, n.ev.resolveAliases(value))
+		})
+	}
+}
+
+func (n yamlAliasNode) Member(name string) (Node, bool) {
+	var found Node
+	n.eachMember(n.node, map[*yaml.Node]bool{}, func(key, value *yaml.Node) bool {
+		if key.Value == name {
+			found = n.ev.resolveAliases(value)
+			return false
+		}
+		return true
+	})
+	return found, found != nil
+}
+
+func (n yamlAliasNode) Key(name string) Node {
+	var found Node
+	n.eachMember(n.node, map[*yaml.Node]bool{}, func(key, value *yaml.Node) bool {
+		if key.Value == name {
+			found = yamlAliasNode{yamlNode{key, n.cfg}, n.ev}
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// eachMember yields the key and value of each member of a mapping, including those it
+// gets from merge keys, in order of precedence: first its own members, then those of each
+// mapping merged into it, in turn. A name may be yielded more than once, in which case the
+// first one takes precedence.
+func (n yamlAliasNode) eachMember(mapping *yaml.Node, visited map[*yaml.Node]bool, yield func(key, value *yaml.Node) bool) bool {
+	if mapping.Kind != yaml.MappingNode || visited[mapping] {
+		return true
+	}
+	visited[mapping] = true
+	var merges []*yaml.Node
+	for i := 1; i < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i-1], mapping.Content[i]
+		if key.Kind == yaml.ScalarNode && key.ShortTag() == "!!merge" {
+			merges = append(merges, value)
+			continue
+		}
+		if !yield(key, value) {
+			return false
+		}
+	}
+	for _, merge := range merges {
+		sources := []*yaml.Node{merge}
+		if merge.Kind == yaml.SequenceNode {
+			sources = merge.Content
+		}
+		for _, source := range sources {
+			// looking up merged members isn't an expansion; resolving their values is
+			if source.Kind == yaml.AliasNode && source.Alias != nil {
+				source = source.Alias
+			}
+			if !n.eachMember(source, visited, yield) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isCycle reports whether the node at loc is also one of its ancestors, which with
+// aliases resolved makes the document infinitely deep.
+func (loc *location) isCycle() bool {
+	id := loc.node.Identity()
+	for ancestor := loc.parent; ancestor != nil; ancestor = ancestor.parent {
+		if ancestor.node != nil && ancestor.node.Identity() == id {
+			return true
+		}
+	}
+	return false
+}
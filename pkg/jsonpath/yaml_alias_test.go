@@ -0,0 +1,271 @@
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAliasResolution(t *testing.T) {
+	const document = `
+defaults: &defaults
+  timeout: 30
+  retries: 3
+extra: &extra
+  retries: 5
+  verbose: true
+servers:
+  - &primary
+    name: primary
+    <<: *defaults
+  - name: secondary
+    <<: [*extra, *defaults]
+    timeout: 60
+  - *primary
+tags: &tags [a, b]
+copy: *tags
+plain: {retries: 3, name: primary, timeout: 30}
+`
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "Name through an alias",
+			input:    "$.servers[2].name",
+			expected: []string{"primary"},
+		},
+		{
+			name:     "Wildcard through an alias",
+			input:    "$.copy[*]",
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "Merged member",
+			input:    "$.servers[0].timeout",
+			expected: []string{"30"},
+		},
+		{
+			name:     "Own members take precedence over merged ones, and earlier sources over later ones",
+			input:    "$.servers[1][*]",
+			expected: []string{"secondary", "60", "5", "true"},
+		},
+		{
+			name:     "Filter on merged members",
+			input:    "$.servers[?@.retries == 3].name",
+			expected: []string{"primary", "primary"},
+		},
+		{
+			name:     "Descendants through each alias",
+			input:    "$.servers..retries",
+			expected: []string{"3", "5", "3"},
+		},
+		{
+			name:     "Compare through aliases",
+			input:    "$.servers[?@ == $.servers[0]].name",
+			expected: []string{"primary", "primary"},
+		},
+		{
+			name:     "Compare with merged members",
+			input:    "$.servers[?@ == $.plain].name",
+			expected: []string{"primary", "primary"},
+		},
+		{
+			name:     "Merged member names",
+			input:    "$.servers[0].*~",
+			expected: []string{"name", "timeout", "retries"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte(document), &root); err != nil {
+				t.Fatal(err)
+			}
+			path, err := NewPath(test.input, config.WithAliasResolution(), config.WithPropertyNameExtension())
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			actual := []string{}
+			for _, node := range path.Query(&root) {
+				actual = append(actual, node.Value)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("Expected:\n%v\nGot:\n%v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAliasResolutionDisabled(t *testing.T) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte("a: &a {b: 1}\nc: *a\nd: {<<: *a}"), &root); err != nil {
+		t.Fatal(err)
+	}
+	path, err := NewPath("$[*].b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := path.Query(&root); len(actual) != 1 {
+		t.Errorf("Expected aliases to be left alone, got %d nodes", len(actual))
+	}
+}
+
+func TestAliasCycle(t *testing.T) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte("a: &a\n  b: 1\n  c: *a\n"), &root); err != nil {
+		t.Fatal(err)
+	}
+	path, err := NewPath("$..b", config.WithAliasResolution())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := path.Query(&root); len(actual) != 1 {
+		t.Errorf("Expected a single node, got %d", len(actual))
+	}
+}
+
+func TestAliasDescendants(t *testing.T) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte("a: &x {v: 1}\nb: *x\n"), &root); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"$['a']['v']", "$['b']['v']"}
+	for _, input := range []string{"$..v", "$.*.v"} {
+		path, err := NewPath(input, config.WithAliasResolution())
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := []string{}
+		for _, result := range path.QueryWithPaths(&root) {
+			actual = append(actual, result.Path.String())
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected %s to select %v, got %v", input, expected, actual)
+		}
+	}
+}
+
+func TestAliasExpansionLimit(t *testing.T) {
+	// each level refers to the previous one ten times
+	var document strings.Builder
+	document.WriteString("l0: &l0 [lol]\n")
+	for i := 1; i < 8; i++ {
+		aliases := strings.TrimSuffix(strings.Repeat("*l"+string(rune('0'+i-1))+", ", 10), ", ")
+		document.WriteString("l" + string(rune('0'+i)) + ": &l" + string(rune('0'+i)) + " [" + aliases + "]\n")
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(document.String()), &root); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts []config.Option
+		max  int
+	}{
+		{name: "Default", opts: []config.Option{config.WithAliasResolution()}, max: config.DefaultMaxAliasExpansions},
+		{name: "Configured", opts: []config.Option{config.WithAliasResolution(), config.WithMaxAliasExpansions(50)}, max: 50},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := NewPath("$..[?@ == 'lol']", test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = path.QueryContext(t.Context(), &root)
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) || limitErr.Limit != LimitAliasExpansions || limitErr.Max != test.max {
+				t.Errorf("Expected the alias expansions limit of %d to be hit, got %v", test.max, err)
+			}
+		})
+	}
+}
+
+func TestAliasExpansionsCounted(t *testing.T) {
+	var root yaml.Node
+	document := "- base: &b {x: 1}\n  m: {a: *b, c: *b, d: *b}\n  n: {<<: *b, y: 2}\n"
+	if err := yaml.Unmarshal([]byte(document), &root); err != nil {
+		t.Fatal(err)
+	}
+	// counting the members of m, and looking up those n merges, reads none of the aliases
+	path, err := NewPath("$[?length(@.m) == 3 && length(@.n) == 2 && @.n.x == 1].m.a.x", config.WithAliasResolution(), config.WithMaxAliasExpansions(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := path.QueryContext(t.Context(), &root)
+	if err != nil {
+		t.Fatalf("Expected a single alias expansion, got %v", err)
+	}
+	if len(result) != 1 || result[0].Value != "1" {
+		t.Errorf("Expected @.m.a.x, got %d nodes", len(result))
+	}
+}
+
+// billionLaughs returns a document with two separate trees that expand to 10^levels nodes
+// each, under x and y, and an alias of x under z.
+func billionLaughs(levels int) string {
+	var document strings.Builder
+	for _, tree := range []string{"x", "y"} {
+		document.WriteString(tree + "0: &" + tree + "0 [lol, lol, lol, lol, lol, lol, lol, lol, lol, lol]\n")
+		for i := 1; i < levels; i++ {
+			aliases := strings.TrimSuffix(strings.Repeat(fmt.Sprintf("*%s%d, ", tree, i-1), 10), ", ")
+			document.WriteString(fmt.Sprintf("%s%d: &%s%d [%s]\n", tree, i, tree, i, aliases))
+		}
+		document.WriteString(fmt.Sprintf("%s: *%s%d\n", tree, tree, levels-1))
+	}
+	document.WriteString(fmt.Sprintf("z: *x%d\n", levels-1))
+	return document.String()
+}
+
+func TestAliasComparisonLimit(t *testing.T) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(billionLaughs(9)), &root); err != nil {
+		t.Fatal(err)
+	}
+	// the filter is evaluated against the single element of [root]
+	wrapped := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{root.Content[0]}}
+
+	tests := []struct {
+		name     string
+		input    string
+		opts     []config.Option
+		expected int
+		limit    bool
+	}{
+		{name: "Without alias resolution", input: "$[?$[0].x == $[0].y]", expected: 0},
+		{name: "Aliases of the same anchor, without alias resolution", input: "$[?$[0].x == $[0].z]", expected: 1},
+		{name: "Over the limit", input: "$[?$[0].x == $[0].y]", opts: []config.Option{config.WithAliasResolution(), config.WithMaxAliasExpansions(1000)}, limit: true},
+		{name: "Over the default limit", input: "$[?$[0].x == $[0].y]", opts: []config.Option{config.WithAliasResolution()}, limit: true},
+		{name: "The same node", input: "$[?$[0].x == $[0].z]", opts: []config.Option{config.WithAliasResolution(), config.WithMaxAliasExpansions(10)}, expected: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path, err := NewPath(test.input, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := path.QueryContext(t.Context(), wrapped)
+			var limitErr *LimitError
+			if test.limit {
+				if !errors.As(err, &limitErr) || limitErr.Limit != LimitAliasExpansions {
+					t.Errorf("Expected the alias expansions limit to be hit, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result) != test.expected {
+				t.Errorf("Expected %d nodes, got %d", test.expected, len(result))
+			}
+		})
+	}
+}
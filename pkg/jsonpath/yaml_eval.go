@@ -1,26 +1,21 @@
 package jsonpath
 
 import (
+	"cmp"
 	"fmt"
-	"gopkg.in/yaml.v3"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"math"
+	"math/big"
 	"reflect"
 	"regexp"
-	"strconv"
+	"time"
 	"unicode/utf8"
 )
 
 func (l literal) Equals(value literal) bool {
-	if l.integer != nil && value.integer != nil {
-		return *l.integer == *value.integer
-	}
-	if l.float64 != nil && value.float64 != nil {
-		return *l.float64 == *value.float64
-	}
-	if l.integer != nil && value.float64 != nil {
-		return float64(*l.integer) == *value.float64
-	}
-	if l.float64 != nil && value.integer != nil {
-		return *l.float64 == float64(*value.integer)
+	if l.isNumber() && value.isNumber() {
+		c, ok := compareNumbers(l, value)
+		return ok && c == 0
 	}
 	if l.string != nil && value.string != nil {
 		return *l.string == *value.string
@@ -31,6 +26,9 @@ func (l literal) Equals(value literal) bool {
 	if l.null != nil && value.null != nil {
 		return *l.null == *value.null
 	}
+	if t, other, ok := timestamps(l, value); ok {
+		return t.Equal(other)
+	}
 	if l.node != nil && value.node != nil {
 		return equalsNode(l.node, value.node)
 	}
@@ -40,59 +38,13 @@ func (l literal) Equals(value literal) bool {
 	return false
 }
 
-func equalsNode(a *yaml.Node, b *yaml.Node) bool {
-	// decode into interfaces, then compare
-	if a.Tag != b.Tag {
-		return false
-	}
-	switch a.Tag {
-	case "!!str":
-		return a.Value == b.Value
-	case "!!int":
-		return a.Value == b.Value
-	case "!!float":
-		return a.Value == b.Value
-	case "!!bool":
-		return a.Value == b.Value
-	case "!!null":
-		return a.Value == b.Value
-	case "!!seq":
-		if len(a.Content) != len(b.Content) {
-			return false
-		}
-		for i := 0; i < len(a.Content); i++ {
-			if !equalsNode(a.Content[i], b.Content[i]) {
-				return false
-			}
-		}
-	case "!!map":
-		if len(a.Content) != len(b.Content) {
-			return false
-		}
-		for i := 0; i < len(a.Content); i += 2 {
-			if !equalsNode(a.Content[i], b.Content[i]) {
-				return false
-			}
-			if !equalsNode(a.Content[i+1], b.Content[i+1]) {
-				return false
-			}
-		}
-	}
-	return true
-}
-
 func (l literal) LessThan(value literal) bool {
-	if l.integer != nil && value.integer != nil {
-		return *l.integer < *value.integer
-	}
-	if l.float64 != nil && value.float64 != nil {
-		return *l.float64 < *value.float64
+	if l.isNumber() && value.isNumber() {
+		c, ok := compareNumbers(l, value)
+		return ok && c < 0
 	}
-	if l.integer != nil && value.float64 != nil {
-		return float64(*l.integer) < *value.float64
-	}
-	if l.float64 != nil && value.integer != nil {
-		return *l.float64 < float64(*value.integer)
+	if t, other, ok := timestamps(l, value); ok {
+		return t.Before(other)
 	}
 	if l.string != nil && value.string != nil {
 		return *l.string < *value.string
@@ -104,21 +56,87 @@ func (l literal) LessThanOrEqual(value literal) bool {
 	return l.LessThan(value) || l.Equals(value)
 }
 
-func (c comparable) Evaluate(idx index, node *yaml.Node, root *yaml.Node) literal {
+// timestamps returns the times two literals hold, if at least one is a timestamp and the
+// other is a timestamp too or a string that parses as one, so that they compare
+// chronologically.
+func timestamps(a literal, b literal) (time.Time, time.Time, bool) {
+	if a.time == nil && b.time == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	at, aok := a.toTime()
+	bt, bok := b.toTime()
+	return at, bt, aok && bok
+}
+
+func (l literal) toTime() (time.Time, bool) {
+	switch {
+	case l.time != nil:
+		return *l.time, true
+	case l.string != nil:
+		return config.ParseTimestamp(*l.string)
+	}
+	return time.Time{}, false
+}
+
+func (l literal) isNumber() bool {
+	return l.integer != nil || l.float64 != nil || l.bigInt != nil
+}
+
+// compareNumbers compares two number literals by value, exactly, whichever way each is
+// represented. It returns -1, 0 or +1 as a is less than, equal to or greater than b.
+//
+// Infinities compare as they do in IEEE 754: beyond every finite number, and equal to an
+// infinity of the same sign. NaN is equal to NaN, so that a value is always equal to
+// itself, but it is unordered with respect to any other number: ok is then false, and so
+// comparing NaN to a number with ==, <, <=, > or >= is false, and with != true.
+func compareNumbers(a literal, b literal) (c int, ok bool) {
+	if a.integer != nil && b.integer != nil {
+		return cmp.Compare(*a.integer, *b.integer), true
+	}
+	aNaN := a.float64 != nil && math.IsNaN(*a.float64)
+	bNaN := b.float64 != nil && math.IsNaN(*b.float64)
+	if aNaN || bNaN {
+		return 0, aNaN && bNaN
+	}
+	if a.bigInt == nil && b.bigInt == nil {
+		// integer literals within ±MaxSafeFloat convert to float64 exactly
+		return cmp.Compare(a.toFloat64(), b.toFloat64()), true
+	}
+	return a.toBigFloat().Cmp(b.toBigFloat()), true
+}
+
+func (l literal) toFloat64() float64 {
+	if l.integer != nil {
+		return float64(*l.integer)
+	}
+	return *l.float64
+}
+
+func (l literal) toBigFloat() *big.Float {
+	switch {
+	case l.integer != nil:
+		return new(big.Float).SetInt64(int64(*l.integer))
+	case l.bigInt != nil:
+		return new(big.Float).SetInt(l.bigInt)
+	}
+	return big.NewFloat(*l.float64)
+}
+
+func (c comparable) Evaluate(node *location, ev *evaluation) literal {
 	if c.literal != nil {
 		return *c.literal
 	}
 	if c.singularQuery != nil {
-		return c.singularQuery.Evaluate(idx, node, root)
+		return c.singularQuery.Evaluate(node, ev)
 	}
 	if c.functionExpr != nil {
-		return c.functionExpr.Evaluate(idx, node, root)
+		return c.functionExpr.Evaluate(node, ev)
 	}
 	return literal{}
 }
 
-func (e functionExpr) length(idx index, node *yaml.Node, root *yaml.Node) literal {
-	args := e.args[0].Eval(idx, node, root)
+func (e functionExpr) length(node *location, ev *evaluation) literal {
+	args := e.args[0].Eval(node, ev)
 	if args.kind != functionArgTypeLiteral {
 		return literal{}
 	}
@@ -138,20 +156,22 @@ func (e functionExpr) length(idx index, node *yaml.Node, root *yaml.Node) litera
 	//Nothing.
 
 	if args.literal.node != nil {
-		switch args.literal.node.Kind {
-		case yaml.SequenceNode:
-			res := len(args.literal.node.Content)
-			return literal{integer: &res}
-		case yaml.MappingNode:
-			res := len(args.literal.node.Content) / 2
+		switch args.literal.node.Kind() {
+		case NodeArray, NodeObject:
+			res := args.literal.node.Len()
 			return literal{integer: &res}
 		}
 	}
 	return literal{}
 }
 
-func (e functionExpr) count(idx index, node *yaml.Node, root *yaml.Node) literal {
-	args := e.args[0].Eval(idx, node, root)
+func (e functionExpr) count(node *location, ev *evaluation) literal {
+	if e.args[0].filterQuery != nil {
+		// count the nodes directly, rather than resolving each to a value
+		res := e.args[0].filterQuery.Count(node, ev)
+		return literal{integer: &res}
+	}
+	args := e.args[0].Eval(node, ev)
 	if args.kind == functionArgTypeNodes {
 		res := len(args.nodes)
 		return literal{integer: &res}
@@ -161,9 +181,9 @@ func (e functionExpr) count(idx index, node *yaml.Node, root *yaml.Node) literal
 	return literal{integer: &res}
 }
 
-func (e functionExpr) match(idx index, node *yaml.Node, root *yaml.Node) literal {
-	arg1 := e.args[0].Eval(idx, node, root)
-	arg2 := e.args[1].Eval(idx, node, root)
+func (e functionExpr) match(node *location, ev *evaluation) literal {
+	arg1 := e.args[0].Eval(node, ev)
+	arg2 := e.args[1].Eval(node, ev)
 	if arg1.kind != functionArgTypeLiteral || arg2.kind != functionArgTypeLiteral {
 		return literal{}
 	}
@@ -174,9 +194,9 @@ func (e functionExpr) match(idx index, node *yaml.Node, root *yaml.Node) literal
 	return literal{bool: &matched}
 }
 
-func (e functionExpr) search(idx index, node *yaml.Node, root *yaml.Node) literal {
-	arg1 := e.args[0].Eval(idx, node, root)
-	arg2 := e.args[1].Eval(idx, node, root)
+func (e functionExpr) search(node *location, ev *evaluation) literal {
+	arg1 := e.args[0].Eval(node, ev)
+	arg2 := e.args[1].Eval(node, ev)
 	if arg1.kind != functionArgTypeLiteral || arg2.kind != functionArgTypeLiteral {
 		return literal{}
 	}
@@ -187,7 +207,7 @@ func (e functionExpr) search(idx index, node *yaml.Node, root *yaml.Node) litera
 	return literal{bool: &matched}
 }
 
-func (e functionExpr) value(idx index, node *yaml.Node, root *yaml.Node) literal {
+func (e functionExpr) value(node *location, ev *evaluation) literal {
 	//	2.4.8.  value() Function Extension
 	//
 	//Parameters:
@@ -204,7 +224,7 @@ func (e functionExpr) value(idx index, node *yaml.Node, root *yaml.Node) literal
 	//*  If the argument is the empty nodelist or contains multiple nodes,
 	//	the result is Nothing.
 
-	nodesType := e.args[0].Eval(idx, node, root)
+	nodesType := e.args[0].Eval(node, ev)
 	if nodesType.kind == functionArgTypeLiteral {
 		return *nodesType.literal
 	} else if nodesType.kind == functionArgTypeNodes && len(nodesType.nodes) == 1 {
@@ -213,66 +233,45 @@ func (e functionExpr) value(idx index, node *yaml.Node, root *yaml.Node) literal
 	return literal{}
 }
 
-func nodeToLiteral(node *yaml.Node) literal {
-	switch node.Tag {
-	case "!!str":
-		return literal{string: &node.Value}
-	case "!!int":
-		i, _ := strconv.Atoi(node.Value)
-		return literal{integer: &i}
-	case "!!float":
-		f, _ := strconv.ParseFloat(node.Value, 64)
-		return literal{float64: &f}
-	case "!!bool":
-		b, _ := strconv.ParseBool(node.Value)
-		return literal{bool: &b}
-	case "!!null":
-		b := true
-		return literal{null: &b}
-	default:
-		return literal{node: node}
-	}
-}
-
-func (e functionExpr) Evaluate(idx index, node *yaml.Node, root *yaml.Node) literal {
+func (e functionExpr) Evaluate(node *location, ev *evaluation) literal {
 	switch e.funcType {
 	case functionTypeLength:
-		return e.length(idx, node, root)
+		return e.length(node, ev)
 	case functionTypeCount:
-		return e.count(idx, node, root)
+		return e.count(node, ev)
 	case functionTypeMatch:
-		return e.match(idx, node, root)
+		return e.match(node, ev)
 	case functionTypeSearch:
-		return e.search(idx, node, root)
+		return e.search(node, ev)
 	case functionTypeValue:
-		return e.value(idx, node, root)
+		return e.value(node, ev)
 	}
 	return literal{}
 }
 
-func (q singularQuery) Evaluate(idx index, node *yaml.Node, root *yaml.Node) literal {
+func (q singularQuery) Evaluate(node *location, ev *evaluation) literal {
 	if q.relQuery != nil {
-		return q.relQuery.Evaluate(idx, node, root)
+		return q.relQuery.Evaluate(node, ev)
 	}
 	if q.absQuery != nil {
-		return q.absQuery.Evaluate(idx, node, root)
+		return q.absQuery.Evaluate(node, ev)
 	}
 	return literal{}
 }
 
-func (q relQuery) Evaluate(idx index, node *yaml.Node, root *yaml.Node) literal {
-	result := q.Query(idx, node, root)
+func (q relQuery) Evaluate(node *location, ev *evaluation) literal {
+	result := q.Query(node, ev)
 	if len(result) == 1 {
-		return nodeToLiteral(result[0])
+		return nodeToLiteral(result[0].node)
 	}
 	return literal{}
 
 }
 
-func (q absQuery) Evaluate(idx index, node *yaml.Node, root *yaml.Node) literal {
-	result := q.Query(idx, root, root)
+func (q absQuery) Evaluate(node *location, ev *evaluation) literal {
+	result := q.Query(ev.root, ev)
 	if len(result) == 1 {
-		return nodeToLiteral(result[0])
+		return nodeToLiteral(result[0].node)
 	}
 	return literal{}
 }
@@ -1,6 +1,8 @@
 package jsonpath
 
 import (
+	"math"
+	"math/big"
 	"reflect"
 	"testing"
 
@@ -80,6 +82,42 @@ func TestLiteralEquals(t *testing.T) {
 			literal2: literal{string: stringPtr("10")},
 			expected: false,
 		},
+		{
+			name:     "Integer and float",
+			literal1: literal{integer: intPtr(2)},
+			literal2: literal{float64: float64Ptr(2.0)},
+			expected: true,
+		},
+		{
+			name:     "Big integers compare exactly",
+			literal1: literal{bigInt: big.NewInt(9007199254740993)},
+			literal2: literal{float64: float64Ptr(9007199254740992)},
+			expected: false,
+		},
+		{
+			name:     "Equal big integers",
+			literal1: literal{bigInt: big.NewInt(9007199254740993)},
+			literal2: literal{bigInt: big.NewInt(9007199254740993)},
+			expected: true,
+		},
+		{
+			name:     "Equal infinities",
+			literal1: literal{float64: float64Ptr(math.Inf(1))},
+			literal2: literal{float64: float64Ptr(math.Inf(1))},
+			expected: true,
+		},
+		{
+			name:     "NaN equals NaN",
+			literal1: literal{float64: float64Ptr(math.NaN())},
+			literal2: literal{float64: float64Ptr(math.NaN())},
+			expected: true,
+		},
+		{
+			name:     "NaN does not equal a number",
+			literal1: literal{float64: float64Ptr(math.NaN())},
+			literal2: literal{integer: intPtr(0)},
+			expected: false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -129,6 +167,30 @@ func TestLiteralLessThan(t *testing.T) {
 			literal2: literal{string: stringPtr("10")},
 			expected: false,
 		},
+		{
+			name:     "Float less than big integer",
+			literal1: literal{float64: float64Ptr(9007199254740992)},
+			literal2: literal{bigInt: big.NewInt(9007199254740993)},
+			expected: true,
+		},
+		{
+			name:     "Big integer less than infinity",
+			literal1: literal{bigInt: new(big.Int).Lsh(big.NewInt(1), 2000)},
+			literal2: literal{float64: float64Ptr(math.Inf(1))},
+			expected: true,
+		},
+		{
+			name:     "NaN is unordered",
+			literal1: literal{float64: float64Ptr(math.NaN())},
+			literal2: literal{float64: float64Ptr(math.Inf(1))},
+			expected: false,
+		},
+		{
+			name:     "Nothing is less than NaN",
+			literal1: literal{float64: float64Ptr(math.Inf(-1))},
+			literal2: literal{float64: float64Ptr(math.NaN())},
+			expected: false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -235,7 +297,7 @@ func TestComparableEvaluate(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result := tc.comparable.Evaluate(&_index{}, tc.node, tc.root)
+			result := tc.comparable.Evaluate(&location{node: NewYAMLNode(tc.node)}, newEvaluation(&location{node: NewYAMLNode(tc.root)}))
 			if !reflect.DeepEqual(result, tc.expected) {
 				t.Errorf("Expected %v, but got %v", tc.expected, result)
 			}
@@ -269,7 +331,7 @@ func TestSingularQueryEvaluate(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result := tc.query.Evaluate(&_index{}, tc.node, tc.root)
+			result := tc.query.Evaluate(&location{node: NewYAMLNode(tc.node)}, newEvaluation(&location{node: NewYAMLNode(tc.root)}))
 			if !reflect.DeepEqual(result, tc.expected) {
 				t.Errorf("Expected %v, but got %v", tc.expected, result)
 			}
@@ -303,7 +365,7 @@ func TestRelQueryEvaluate(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result := tc.query.Evaluate(&_index{}, tc.node, tc.root)
+			result := tc.query.Evaluate(&location{node: NewYAMLNode(tc.node)}, newEvaluation(&location{node: NewYAMLNode(tc.root)}))
 			if !reflect.DeepEqual(result, tc.expected) {
 				t.Errorf("Expected %v, but got %v", tc.expected, result)
 			}
@@ -337,7 +399,7 @@ func TestAbsQueryEvaluate(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result := tc.query.Evaluate(&_index{}, tc.node, tc.root)
+			result := tc.query.Evaluate(&location{node: NewYAMLNode(tc.node)}, newEvaluation(&location{node: NewYAMLNode(tc.root)}))
 			if !reflect.DeepEqual(result, tc.expected) {
 				t.Errorf("Expected %v, but got %v", tc.expected, result)
 			}
@@ -0,0 +1,197 @@
+package jsonpath
+
+import (
+	"errors"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+	"iter"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// yamlNode adapts a *yaml.Node to Node. Scalars are typed by their tag, or by the tag
+// resolver cfg sets for it; a mapping's keys are compared by their raw value.
+type yamlNode struct {
+	node *yaml.Node
+	cfg  config.Config
+}
+
+// defaultConfig is the configuration of nodes created by NewYAMLNode.
+var defaultConfig = config.New()
+
+// NewYAMLNode returns the Node for a *yaml.Node. This is the document model Query and the
+// other *yaml.Node methods of JSONPath evaluate against. Scalars are resolved with the
+// default tag resolvers, see config.WithTagResolver.
+func NewYAMLNode(node *yaml.Node) Node {
+	return yamlNode{node, defaultConfig}
+}
+
+// child returns the Node for a node within n.
+func (n yamlNode) child(node *yaml.Node) yamlNode {
+	return yamlNode{node, n.cfg}
+}
+
+// yamlNodeOf returns the *yaml.Node a Node returned by NewYAMLNode wraps.
+func yamlNodeOf(n Node) *yaml.Node {
+	if aliased, ok := n.(yamlAliasNode); ok {
+		return aliased.node
+	}
+	return n.(yamlNode).node
+}
+
+func (n yamlNode) Kind() NodeKind {
+	if v, ok := n.resolveTag(); ok {
+		if kind := (valueNode{v: v}).Kind(); kind != NodeArray && kind != NodeObject {
+			return kind
+		}
+		return NodeOther
+	}
+	switch n.node.Kind {
+	case yaml.MappingNode:
+		return NodeObject
+	case yaml.SequenceNode:
+		return NodeArray
+	case yaml.ScalarNode:
+		switch n.node.Tag {
+		case "!!str":
+			return NodeString
+		case "!!int", "!!float":
+			return NodeNumber
+		case "!!bool":
+			return NodeBool
+		case "!!null":
+			return NodeNull
+		}
+	}
+	return NodeOther
+}
+
+// Value returns the value of a scalar resolved according to its tag. Scalars with other
+// tags are represented by their raw value. Numbers are resolved by yamlNumber.
+func (n yamlNode) Value() any {
+	if v, ok := n.resolveTag(); ok {
+		return v
+	}
+	switch n.node.Tag {
+	case "!!int", "!!float":
+		return yamlNumber(n.node.Tag, n.node.Value)
+	case "!!bool":
+		b, _ := strconv.ParseBool(n.node.Value)
+		return b
+	case "!!null":
+		return nil
+	}
+	return n.node.Value
+}
+
+// resolveTag returns the value the tag resolver for a scalar's tag gives it, if any.
+func (n yamlNode) resolveTag() (any, bool) {
+	if n.node.Kind != yaml.ScalarNode || n.cfg == nil {
+		return nil, false
+	}
+	if resolver := n.cfg.TagResolver(n.node.ShortTag()); resolver != nil {
+		return resolver(n.node)
+	}
+	return nil, false
+}
+
+// unresolvedTag returns the tag of the YAML scalar n adapts if no tag resolver resolves
+// it, so that its raw value is only compared with those of scalars with the same tag. It
+// returns "" for other nodes.
+func unresolvedTag(n Node) string {
+	var y yamlNode
+	switch n := n.(type) {
+	case yamlNode:
+		y = n
+	case yamlAliasNode:
+		y = n.yamlNode
+	default:
+		return ""
+	}
+	if _, ok := y.resolveTag(); ok || y.node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return y.node.ShortTag()
+}
+
+func (n yamlNode) Len() int {
+	switch n.node.Kind {
+	case yaml.MappingNode:
+		return len(n.node.Content) / 2
+	case yaml.SequenceNode:
+		return len(n.node.Content)
+	}
+	return 0
+}
+
+func (n yamlNode) Index(i int) Node {
+	return n.child(n.node.Content[i])
+}
+
+func (n yamlNode) Members() iter.Seq2[string, Node] {
+	return func(yield func(string, Node) bool) {
+		// in a mapping node, keys and values alternate
+		for i := 1; i < len(n.node.Content); i += 2 {
+			if !yield(n.node.Content[i-1].Value, n.child(n.node.Content[i])) {
+				return
+			}
+		}
+	}
+}
+
+func (n yamlNode) Member(name string) (Node, bool) {
+	for i := 1; i < len(n.node.Content); i += 2 {
+		if n.node.Content[i-1].Value == name {
+			return n.child(n.node.Content[i]), true
+		}
+	}
+	return nil, false
+}
+
+// Key returns the key node of the named member of a mapping.
+func (n yamlNode) Key(name string) Node {
+	for i := 0; i+1 < len(n.node.Content); i += 2 {
+		if n.node.Content[i].Value == name {
+			return n.child(n.node.Content[i])
+		}
+	}
+	return nil
+}
+
+func (n yamlNode) Identity() any {
+	return n.node
+}
+
+// yamlNumber resolves the value of an !!int or !!float scalar. It accepts every form
+// yaml.v3 decodes: decimal, 0x, 0o, 0b and 0-prefixed octal integers, digits separated by
+// underscores, an explicit + sign, and .inf, -.inf and .nan. Integers are returned as an
+// int, or as a *big.Int if they don't fit one. This includes plain integers too large for
+// yaml.v3 to decode as anything but an !!float, so that they are compared exactly. Other
+// floats are returned as a float64, and scalars that aren't numbers as their raw value.
+func yamlNumber(tag string, value string) any {
+	if i, ok := new(big.Int).SetString(value, 0); ok {
+		switch {
+		case !i.IsInt64() || i.Int64() < math.MinInt || i.Int64() > math.MaxInt:
+			return i
+		case tag == "!!int":
+			return int(i.Int64())
+		default:
+			return float64(i.Int64())
+		}
+	}
+	switch strings.ToLower(value) {
+	case ".inf", "+.inf":
+		return math.Inf(1)
+	case "-.inf":
+		return math.Inf(-1)
+	case ".nan":
+		return math.NaN()
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return value
+	}
+	return f
+}
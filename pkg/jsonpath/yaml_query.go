@@ -1,6 +1,8 @@
 package jsonpath
 
 import (
+	"context"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
 	"gopkg.in/yaml.v3"
 )
 
@@ -8,241 +10,351 @@ type Evaluator interface {
 	Query(current *yaml.Node, root *yaml.Node) []*yaml.Node
 }
 
-type index interface {
-	setPropertyKey(key *yaml.Node, value *yaml.Node)
-	getPropertyKey(key *yaml.Node) *yaml.Node
+// location is a node together with how the evaluator reached it: its parent and the
+// member name or array index it was found under. It is what lets us recover the
+// Normalized Path of a match, and what the "~" property name extension resolves against.
+type location struct {
+	node   Node
+	parent *location
+	// element is the member name or array index node was found under in its parent
+	element PathElement
+	// propertyName is true when node is the key of a member (selected via "~")
+	propertyName bool
+	// depth is the number of ancestors of node
+	depth int
 }
 
-type _index struct {
-	propertyKeys map[*yaml.Node]*yaml.Node
+// memberLocation returns the location of the value of the named member of the object at parent.
+func memberLocation(parent *location, name string, value Node) *location {
+	return &location{node: value, parent: parent, element: NameElement(name), depth: parent.depth + 1}
 }
 
-func (i *_index) setPropertyKey(key *yaml.Node, value *yaml.Node) {
-	if i != nil && i.propertyKeys != nil {
-		i.propertyKeys[key] = value
+// elementLocation returns the location of the i'th element of the array at parent.
+func elementLocation(parent *location, i int) *location {
+	return &location{node: parent.node.Index(i), parent: parent, element: IndexElement(i), depth: parent.depth + 1}
+}
+
+// jsonPathAST can be Evaluated
+var _ Evaluator = jsonPathAST{}
+
+// evaluation is the state of a single evaluation of a query: the root it runs against,
+// and the budget it must stay within.
+type evaluation struct {
+	root    *location
+	ctx     context.Context
+	options queryOptions
+	// config is what the query was parsed with, and sets how *yaml.Node trees are read
+	config config.Config
+	// visited counts the nodes produced while evaluating, including inside filters
+	visited int
+	// results counts the nodes selected by the query so far
+	results int
+	// aliases is set when YAML aliases are resolved, see config.WithAliasResolution
+	aliases            bool
+	aliasExpansions    int
+	maxAliasExpansions int
+	// filterDepth is the number of filter selectors currently being evaluated
+	filterDepth int
+	// err is set when evaluation had to stop early; once set, every traversal stops
+	err error
+}
+
+func newEvaluation(root *location) *evaluation {
+	return &evaluation{root: root}
+}
+
+// newYAMLEvaluation returns an evaluation of the query against a *yaml.Node tree, as
+// configured by the options the query was parsed with.
+func (p *JSONPath) newYAMLEvaluation(root *yaml.Node) *evaluation {
+	ev := newEvaluation(nil)
+	ev.config = p.config
+	if p.config.AliasResolutionEnabled() {
+		ev.aliases = true
+		ev.maxAliasExpansions = p.config.MaxAliasExpansions()
+	}
+	if root != nil {
+		ev.root = ev.yamlRoot(root)
 	}
+	return ev
+}
+
+// yamlRoot returns the location of a *yaml.Node query argument.
+func (ev *evaluation) yamlRoot(root *yaml.Node) *location {
+	loc := rootLocation(root, ev.config)
+	loc.node = ev.yamlNode(yamlNodeOf(loc.node))
+	return loc
 }
 
-func (i *_index) getPropertyKey(key *yaml.Node) *yaml.Node {
-	if i != nil {
-		return i.propertyKeys[key]
+// yamlNode returns the Node for n, as configured for the evaluation.
+func (ev *evaluation) yamlNode(n *yaml.Node) Node {
+	if ev.aliases {
+		return ev.resolveAliases(n)
 	}
-	return nil
+	return yamlNode{n, ev.config}
 }
 
-// jsonPathAST can be Evaluated
-var _ Evaluator = jsonPathAST{}
+// visit accounts for one more node being visited. It returns false, recording why, if
+// the evaluation should stop.
+func (ev *evaluation) visit() bool {
+	if ev.err != nil {
+		return false
+	}
+	ev.visited++
+	if ev.options.maxVisitedNodes > 0 && ev.visited > ev.options.maxVisitedNodes {
+		ev.err = &LimitError{Limit: LimitVisitedNodes, Max: ev.options.maxVisitedNodes}
+		return false
+	}
+	// checking the context is comparatively expensive, so only do it periodically
+	if ev.ctx != nil && ev.visited%contextCheckInterval == 0 {
+		if err := ev.ctx.Err(); err != nil {
+			ev.err = err
+			return false
+		}
+	}
+	return true
+}
 
-func (q jsonPathAST) Query(current *yaml.Node, root *yaml.Node) []*yaml.Node {
-	idx := _index{
-		propertyKeys: map[*yaml.Node]*yaml.Node{},
+// result accounts for one more node being selected by the query. It returns false,
+// recording why, if the evaluation should stop.
+func (ev *evaluation) result() bool {
+	ev.results++
+	if ev.options.maxResults > 0 && ev.results > ev.options.maxResults {
+		ev.err = &LimitError{Limit: LimitResults, Max: ev.options.maxResults}
+		return false
 	}
-	result := make([]*yaml.Node, 0)
-	// If the top level node is a documentnode, unwrap it
+	return true
+}
+
+func (q jsonPathAST) Query(current *yaml.Node, root *yaml.Node) []*yaml.Node {
+	return nodes(q.query(newEvaluation(rootLocation(root, defaultConfig))))
+}
+
+// rootLocation returns the location of the query argument. If the top level node is a
+// document node, it is unwrapped.
+func rootLocation(root *yaml.Node, cfg config.Config) *location {
 	if root.Kind == yaml.DocumentNode && len(root.Content) == 1 {
 		root = root.Content[0]
 	}
-	result = append(result, root)
+	return &location{node: yamlNode{root, cfg}}
+}
 
-	for _, segment := range q.segments {
-		newValue := []*yaml.Node{}
-		for _, value := range result {
-			newValue = append(newValue, segment.Query(&idx, value, root)...)
-		}
-		result = newValue
+func (q jsonPathAST) query(ev *evaluation) []*location {
+	return collect(q.segments, ev.root, ev)
+}
+
+// each yields the locations selected by the query in order, stopping as soon as yield
+// returns false. It reports whether iteration ran to completion.
+func (q jsonPathAST) each(ev *evaluation, yield func(*location) bool) bool {
+	return eachSegment(q.segments, ev.root, ev, yield)
+}
+
+// eachSegment applies segments in turn starting from value, yielding each final location
+// as soon as it is found. Nodelists are never materialized between segments: the result of
+// applying the remaining segments to each selected node is produced before moving on to the
+// next, which yields exactly the RFC 9535 nodelist order.
+func eachSegment(segments []*segment, value *location, ev *evaluation, yield func(*location) bool) bool {
+	if len(segments) == 0 {
+		return yield(value)
+	}
+	return segments[0].each(value, ev, func(child *location) bool {
+		return eachSegment(segments[1:], child, ev, yield)
+	})
+}
+
+// exists reports whether applying segments to value selects anything, stopping at the first match.
+func exists(segments []*segment, value *location, ev *evaluation) bool {
+	return !eachSegment(segments, value, ev, func(*location) bool {
+		return false
+	})
+}
+
+// count returns how many locations applying segments to value selects, without collecting them.
+func count(segments []*segment, value *location, ev *evaluation) int {
+	n := 0
+	eachSegment(segments, value, ev, func(*location) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// collect returns every location selected by applying segments to value.
+func collect(segments []*segment, value *location, ev *evaluation) []*location {
+	result := []*location{}
+	eachSegment(segments, value, ev, func(loc *location) bool {
+		result = append(result, loc)
+		return true
+	})
+	return result
+}
+
+func nodes(locations []*location) []*yaml.Node {
+	result := make([]*yaml.Node, len(locations))
+	for i, loc := range locations {
+		result[i] = yamlNodeOf(loc.node)
 	}
 	return result
 }
 
-func (s segment) Query(idx index, value *yaml.Node, root *yaml.Node) []*yaml.Node {
+func (s segment) each(value *location, ev *evaluation, yield func(*location) bool) bool {
 	switch s.kind {
 	case segmentKindChild:
-		return s.child.Query(idx, value, root)
+		return s.child.each(value, ev, yield)
 	case segmentKindDescendant:
-		// run the inner segment against this node
-		var result = []*yaml.Node{}
-		children := descend(value, root)
-		for _, child := range children {
-			result = append(result, s.descendant.Query(idx, child, root)...)
+		// run the inner segment against this node and every descendant, making the
+		// results unique by node identity and location, so that a node reached through
+		// more than one alias is yielded at each of them
+		type resultID struct {
+			id      any
+			parent  *location
+			element PathElement
 		}
-		// make children unique by pointer value
-		result = unique(result)
-		return result
+		seen := map[resultID]bool{}
+		return ev.descend(value, func(child *location) bool {
+			return s.descendant.each(child, ev, func(result *location) bool {
+				id := resultID{result.node.Identity(), result.parent, result.element}
+				if seen[id] {
+					return true
+				}
+				seen[id] = true
+				return yield(result)
+			})
+		})
 	case segmentKindProperyName:
-		found := idx.getPropertyKey(value)
-		if found != nil {
-			return []*yaml.Node{found}
+		if value.propertyName {
+			// the property name of a key is the mapping it belongs to
+			if value.parent != nil {
+				return ev.yield(value.parent, yield)
+			}
+			return true
 		}
-		return []*yaml.Node{}
+		if value.parent != nil && value.element.Kind == PathElementName {
+			key := memberKey(value.parent.node, value.element.Name)
+			return ev.yield(&location{node: key, parent: value.parent, element: value.element, propertyName: true, depth: value.depth}, yield)
+		}
+		return true
 	}
 	panic("no segment type")
 }
 
-func unique(nodes []*yaml.Node) []*yaml.Node {
-	// stably returns a new slice containing only the unique elements from nodes
-	res := make([]*yaml.Node, 0)
-	seen := make(map[*yaml.Node]bool)
-	for _, node := range nodes {
-		if _, ok := seen[node]; !ok {
-			res = append(res, node)
-			seen[node] = true
+// children yields the members of an object or the elements of an array.
+func (ev *evaluation) children(value *location, yield func(*location) bool) bool {
+	switch value.node.Kind() {
+	case NodeObject:
+		for name, child := range value.node.Members() {
+			if !ev.visit() || !yield(memberLocation(value, name, child)) {
+				return false
+			}
+		}
+	case NodeArray:
+		for i := range value.node.Len() {
+			if !ev.visit() || !yield(elementLocation(value, i)) {
+				return false
+			}
 		}
 	}
-	return res
+	return true
+}
+
+// yield visits a single selected location and passes it on.
+func (ev *evaluation) yield(loc *location, yield func(*location) bool) bool {
+	return ev.visit() && yield(loc)
 }
 
-func (s innerSegment) Query(idx index, value *yaml.Node, root *yaml.Node) []*yaml.Node {
-	result := []*yaml.Node{}
+// member returns the location of the value of the named member of an object, or nil.
+func member(value *location, name string) *location {
+	if value.node.Kind() != NodeObject {
+		return nil
+	}
+	if child, ok := value.node.Member(name); ok {
+		return memberLocation(value, name, child)
+	}
+	return nil
+}
 
+func (s innerSegment) each(value *location, ev *evaluation, yield func(*location) bool) bool {
 	switch s.kind {
 	case segmentDotWildcard:
 		// Handle wildcard - get all children
-		switch value.Kind {
-		case yaml.MappingNode:
-			// in a mapping node, keys and values alternate
-			// we just want to return the values
-			for i, child := range value.Content {
-				if i%2 == 1 {
-					idx.setPropertyKey(value.Content[i-1], value)
-					idx.setPropertyKey(child, value.Content[i-1])
-					result = append(result, child)
-				}
-			}
-		case yaml.SequenceNode:
-			for _, child := range value.Content {
-				result = append(result, child)
-			}
-		}
-		return result
+		return ev.children(value, yield)
 	case segmentDotMemberName:
 		// Handle member access
-		if value.Kind == yaml.MappingNode {
-			// In YAML mapping nodes, keys and values alternate
-
-			for i := 0; i < len(value.Content); i += 2 {
-				key := value.Content[i]
-				val := value.Content[i+1]
-
-				if key.Value == s.dotName {
-					idx.setPropertyKey(key, value)
-					idx.setPropertyKey(val, key)
-					result = append(result, val)
-					break
-				}
-			}
+		if found := member(value, s.dotName); found != nil {
+			return ev.yield(found, yield)
 		}
-
+		return true
 	case segmentLongHand:
 		// Handle long hand selectors
 		for _, selector := range s.selectors {
-			result = append(result, selector.Query(idx, value, root)...)
+			if !selector.each(value, ev, yield) {
+				return false
+			}
 		}
+		return true
 	default:
 		panic("unknown child segment kind")
 	}
-
-	return result
-
 }
 
-func (s selector) Query(idx index, value *yaml.Node, root *yaml.Node) []*yaml.Node {
+func (s selector) each(value *location, ev *evaluation, yield func(*location) bool) bool {
 	switch s.kind {
 	case selectorSubKindName:
-		if value.Kind != yaml.MappingNode {
-			return nil
-		}
-		// MappingNode children is a list of alternating keys and values
-		var key string
-		for i, child := range value.Content {
-			if i%2 == 0 {
-				key = child.Value
-				continue
-			}
-			if key == s.name && i%2 == 1 {
-				idx.setPropertyKey(value.Content[i], value.Content[i-1])
-				idx.setPropertyKey(value.Content[i-1], value)
-				return []*yaml.Node{child}
-			}
+		if found := member(value, s.name); found != nil {
+			return ev.yield(found, yield)
 		}
 	case selectorSubKindArrayIndex:
-		if value.Kind != yaml.SequenceNode {
-			return nil
+		if value.node.Kind() != NodeArray {
+			return true
 		}
+		length := int64(value.node.Len())
 		// if out of bounds, return nothing
-		if s.index >= int64(len(value.Content)) || s.index < -int64(len(value.Content)) {
-			return nil
+		if s.index >= length || s.index < -length {
+			return true
 		}
 		// if index is negative, go backwards
-		if s.index < 0 {
-			return []*yaml.Node{value.Content[int64(len(value.Content))+s.index]}
-		}
-		return []*yaml.Node{value.Content[s.index]}
+		return ev.yield(elementLocation(value, int(normalize(s.index, length))), yield)
 	case selectorSubKindWildcard:
-		if value.Kind == yaml.SequenceNode {
-			return value.Content
-		} else if value.Kind == yaml.MappingNode {
-			var result []*yaml.Node
-			for i, child := range value.Content {
-				if i%2 == 1 {
-					idx.setPropertyKey(value.Content[i-1], value)
-					idx.setPropertyKey(child, value.Content[i-1])
-					result = append(result, child)
-				}
-			}
-			return result
-		}
-		return nil
+		return ev.children(value, yield)
 	case selectorSubKindArraySlice:
-		if value.Kind != yaml.SequenceNode {
-			return nil
+		if value.node.Kind() != NodeArray {
+			return true
 		}
-		if len(value.Content) == 0 {
-			return nil
+		if value.node.Len() == 0 {
+			return true
 		}
 		step := int64(1)
 		if s.slice.step != nil {
 			step = *s.slice.step
 		}
 		if step == 0 {
-			return nil
+			return true
 		}
 
 		start, end := s.slice.start, s.slice.end
-		lower, upper := bounds(start, end, step, int64(len(value.Content)))
+		lower, upper := bounds(start, end, step, int64(value.node.Len()))
 
-		var result []*yaml.Node
 		if step > 0 {
 			for i := lower; i < upper; i += step {
-				result = append(result, value.Content[i])
+				if !ev.yield(elementLocation(value, int(i)), yield) {
+					return false
+				}
 			}
 		} else {
 			for i := upper; i > lower; i += step {
-				result = append(result, value.Content[i])
+				if !ev.yield(elementLocation(value, int(i)), yield) {
+					return false
+				}
 			}
 		}
-
-		return result
 	case selectorSubKindFilter:
-		var result []*yaml.Node
-		switch value.Kind {
-		case yaml.MappingNode:
-			for i := 1; i < len(value.Content); i += 2 {
-				idx.setPropertyKey(value.Content[i-1], value)
-				idx.setPropertyKey(value.Content[i], value.Content[i-1])
-				if s.filter.Matches(idx, value.Content[i], root) {
-					result = append(result, value.Content[i])
-				}
-			}
-		case yaml.SequenceNode:
-			for _, child := range value.Content {
-				if s.filter.Matches(idx, child, root) {
-					result = append(result, child)
-				}
+		return ev.children(value, func(child *location) bool {
+			if s.filter.Matches(child, ev) {
+				return yield(child)
 			}
-		}
-		return result
+			return ev.err == nil
+		})
 	}
-	return nil
+	return true
 }
 
 func normalize(i, length int64) int64 {
@@ -281,46 +393,55 @@ func bounds(start, end *int64, step, length int64) (int64, int64) {
 	return lower, upper
 }
 
-func (s filterSelector) Matches(idx index, node *yaml.Node, root *yaml.Node) bool {
-	return s.expression.Matches(idx, node, root)
+func (s filterSelector) Matches(node *location, ev *evaluation) bool {
+	if ev.err != nil {
+		return false
+	}
+	ev.filterDepth++
+	defer func() { ev.filterDepth-- }()
+	if ev.options.maxFilterDepth > 0 && ev.filterDepth > ev.options.maxFilterDepth {
+		ev.err = &LimitError{Limit: LimitFilterDepth, Max: ev.options.maxFilterDepth}
+		return false
+	}
+	return s.expression.Matches(node, ev)
 }
 
-func (e logicalOrExpr) Matches(idx index, node *yaml.Node, root *yaml.Node) bool {
+func (e logicalOrExpr) Matches(node *location, ev *evaluation) bool {
 	for _, expr := range e.expressions {
-		if expr.Matches(idx, node, root) {
+		if expr.Matches(node, ev) {
 			return true
 		}
 	}
 	return false
 }
 
-func (e logicalAndExpr) Matches(idx index, node *yaml.Node, root *yaml.Node) bool {
+func (e logicalAndExpr) Matches(node *location, ev *evaluation) bool {
 	for _, expr := range e.expressions {
-		if !expr.Matches(idx, node, root) {
+		if !expr.Matches(node, ev) {
 			return false
 		}
 	}
 	return true
 }
 
-func (e basicExpr) Matches(idx index, node *yaml.Node, root *yaml.Node) bool {
+func (e basicExpr) Matches(node *location, ev *evaluation) bool {
 	if e.parenExpr != nil {
-		result := e.parenExpr.expr.Matches(idx, node, root)
+		result := e.parenExpr.expr.Matches(node, ev)
 		if e.parenExpr.not {
 			return !result
 		}
 		return result
 	} else if e.comparisonExpr != nil {
-		return e.comparisonExpr.Matches(idx, node, root)
+		return e.comparisonExpr.Matches(node, ev)
 	} else if e.testExpr != nil {
-		return e.testExpr.Matches(idx, node, root)
+		return e.testExpr.Matches(node, ev)
 	}
 	return false
 }
 
-func (e comparisonExpr) Matches(idx index, node *yaml.Node, root *yaml.Node) bool {
-	leftValue := e.left.Evaluate(idx, node, root)
-	rightValue := e.right.Evaluate(idx, node, root)
+func (e comparisonExpr) Matches(node *location, ev *evaluation) bool {
+	leftValue := e.left.Evaluate(node, ev)
+	rightValue := e.right.Evaluate(node, ev)
 
 	switch e.op {
 	case equalTo:
@@ -340,12 +461,12 @@ func (e comparisonExpr) Matches(idx index, node *yaml.Node, root *yaml.Node) boo
 	}
 }
 
-func (e testExpr) Matches(idx index, node *yaml.Node, root *yaml.Node) bool {
+func (e testExpr) Matches(node *location, ev *evaluation) bool {
 	var result bool
 	if e.filterQuery != nil {
-		result = len(e.filterQuery.Query(idx, node, root)) > 0
+		result = e.filterQuery.Exists(node, ev)
 	} else if e.functionExpr != nil {
-		funcResult := e.functionExpr.Evaluate(idx, node, root)
+		funcResult := e.functionExpr.Evaluate(node, ev)
 		if funcResult.bool != nil {
 			result = *funcResult.bool
 		} else if funcResult.null == nil {
@@ -358,36 +479,42 @@ func (e testExpr) Matches(idx index, node *yaml.Node, root *yaml.Node) bool {
 	return result
 }
 
-func (q filterQuery) Query(idx index, node *yaml.Node, root *yaml.Node) []*yaml.Node {
+func (q filterQuery) Query(node *location, ev *evaluation) []*location {
 	if q.relQuery != nil {
-		return q.relQuery.Query(idx, node, root)
+		return q.relQuery.Query(node, ev)
 	}
 	if q.jsonPathQuery != nil {
-		return q.jsonPathQuery.Query(node, root)
+		return q.jsonPathQuery.query(ev)
 	}
 	return nil
 }
 
-func (q relQuery) Query(idx index, node *yaml.Node, root *yaml.Node) []*yaml.Node {
-	result := []*yaml.Node{node}
-	for _, seg := range q.segments {
-		var newResult []*yaml.Node
-		for _, value := range result {
-			newResult = append(newResult, seg.Query(idx, value, root)...)
-		}
-		result = newResult
+// Exists reports whether the filter query selects any node, stopping at the first one.
+func (q filterQuery) Exists(node *location, ev *evaluation) bool {
+	if q.relQuery != nil {
+		return exists(q.relQuery.segments, node, ev)
 	}
-	return result
+	if q.jsonPathQuery != nil {
+		return exists(q.jsonPathQuery.segments, ev.root, ev)
+	}
+	return false
 }
 
-func (q absQuery) Query(idx index, node *yaml.Node, root *yaml.Node) []*yaml.Node {
-	result := []*yaml.Node{root}
-	for _, seg := range q.segments {
-		var newResult []*yaml.Node
-		for _, value := range result {
-			newResult = append(newResult, seg.Query(idx, value, root)...)
-		}
-		result = newResult
+// Count returns the number of nodes the filter query selects.
+func (q filterQuery) Count(node *location, ev *evaluation) int {
+	if q.relQuery != nil {
+		return count(q.relQuery.segments, node, ev)
 	}
-	return result
+	if q.jsonPathQuery != nil {
+		return count(q.jsonPathQuery.segments, ev.root, ev)
+	}
+	return 0
+}
+
+func (q relQuery) Query(node *location, ev *evaluation) []*location {
+	return collect(q.segments, node, ev)
+}
+
+func (q absQuery) Query(node *location, ev *evaluation) []*location {
+	return collect(q.segments, ev.root, ev)
 }
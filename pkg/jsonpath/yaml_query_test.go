@@ -5,6 +5,7 @@ import (
 	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/token"
 	"gopkg.in/yaml.v3"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -92,6 +93,85 @@ store:
 `,
 			expected: []string{"Book 1"},
 		},
+		{
+			name:  "YAML number forms",
+			input: "$[?@.v == 31 || @.v == 15 || @.v == 1000 || @.v == 5].name",
+			yaml: `
+- {name: hex, v: 0x1F}
+- {name: octal, v: 0o17}
+- {name: underscores, v: 1_000}
+- {name: binary, v: 0b101}
+- {name: string, v: "31"}
+`,
+			expected: []string{"hex", "octal", "underscores", "binary"},
+		},
+		{
+			name:  "Integers beyond the safe range compare exactly",
+			input: "$[?@.maximum > 9007199254740992].name",
+			yaml: `
+- {name: a, maximum: 9007199254740993}
+- {name: b, maximum: 9007199254740992}
+- {name: c, maximum: 123456789012345678901234567890}
+- {name: d, maximum: 9007199254740994.0}
+`,
+			expected: []string{"a", "c", "d"},
+		},
+		{
+			name:  "Infinities and NaN",
+			input: "$[?@.v > 1e308].name",
+			yaml: `
+- {name: inf, v: .inf}
+- {name: negative, v: -.Inf}
+- {name: nan, v: .nan}
+- {name: max, v: 1.7976931348623157e308}
+`,
+			expected: []string{"inf", "max"},
+		},
+		{
+			name:  "NaN is only equal to NaN",
+			input: "$[?@.a == @.b].name",
+			yaml: `
+- {name: both, a: .nan, b: .NaN}
+- {name: one, a: .nan, b: 0}
+`,
+			expected: []string{"both"},
+		},
+		{
+			name:  "Filter, equals object, different member order",
+			input: "$[?@.a == @.b].n",
+			yaml: `
+- {n: same, a: {x: 1, y: [1, 2]}, b: {y: [1, 2], x: 1}}
+- {n: different, a: {x: 1, y: [1, 2]}, b: {y: [2, 1], x: 1}}
+`,
+			expected: []string{"same"},
+		},
+		{
+			name:  "Filter, equals array, numbers by value",
+			input: "$[?@.a == @.b].n",
+			yaml: `
+- {n: equal, a: [1, {k: 2}], b: [1.0, {k: 2e0}]}
+- {n: string, a: [1], b: ["1"]}
+`,
+			expected: []string{"equal"},
+		},
+		{
+			name:  "Filter, equals, aliases are not resolved by default",
+			input: "$.items[?@ == $.defaults]",
+			yaml: `
+defaults: &defaults {n: a, x: 1}
+items:
+  - *defaults
+  - {x: 1, n: a}
+  - {n: b, x: 1}
+`,
+			expected: []string{"{x: 1, n: a}"},
+		},
+		{
+			name:     "Filter, negation binds to the next expression",
+			input:    "$[?!@.a || @.b && !(@.c)].n",
+			yaml:     "[{n: neither}, {n: a, a: 1}, {n: ab, a: 1, b: 1}, {n: abc, a: 1, b: 1, c: 1}]",
+			expected: []string{"neither", "ab"},
+		},
 	}
 
 	for _, test := range tests {
@@ -286,3 +366,412 @@ paths:
 		})
 	}
 }
+
+func TestQueryWithPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		yaml     string
+		expected []string
+		parents  []string
+		keys     []string
+		indices  []int
+	}{
+		{
+			name:     "Root node",
+			input:    "$",
+			yaml:     "foo",
+			expected: []string{"$"},
+			parents:  []string{""},
+			keys:     []string{""},
+			indices:  []int{0},
+		},
+		{
+			name:  "Member names",
+			input: "$.paths['/pets'].get",
+			yaml: `
+paths:
+  /pets:
+    get: {}
+`,
+			expected: []string{"$['paths']['/pets']['get']"},
+			parents:  []string{"get: {}"},
+			keys:     []string{"get"},
+			indices:  []int{0},
+		},
+		{
+			name:     "Negative array index",
+			input:    "$[-1]",
+			yaml:     "[foo, bar, baz]",
+			expected: []string{"$[2]"},
+			parents:  []string{"[foo, bar, baz]"},
+			keys:     []string{""},
+			indices:  []int{2},
+		},
+		{
+			name:     "Slice with negative step",
+			input:    "$.a[::-1]",
+			yaml:     "a: [x, y]",
+			expected: []string{"$['a'][1]", "$['a'][0]"},
+			parents:  []string{"[x, y]", "[x, y]"},
+			keys:     []string{"", ""},
+			indices:  []int{1, 0},
+		},
+		{
+			name:  "Descendant filter",
+			input: "$..[?@.deprecated]",
+			yaml: `
+tags:
+  - name: a
+    deprecated: true
+  - name: b
+nested:
+  old: {deprecated: true}
+`,
+			expected: []string{"$['tags'][0]", "$['nested']['old']"},
+			parents:  []string{"- name: a\n  deprecated: true\n- name: b", "old: {deprecated: true}"},
+			keys:     []string{"", "old"},
+			indices:  []int{0, 0},
+		},
+		{
+			name:     "Escaped member names",
+			input:    `$["it's", "a\nb"]`,
+			yaml:     "{\"it's\": 1, \"a\\nb\": 2}",
+			expected: []string{`$['it\'s']`, `$['a\nb']`},
+			parents:  []string{"{\"it's\": 1, ? \"a\\nb\" : 2}", "{\"it's\": 1, ? \"a\\nb\" : 2}"},
+			keys:     []string{"it's", "a\nb"},
+			indices:  []int{0, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var root yaml.Node
+			err := yaml.Unmarshal([]byte(test.yaml), &root)
+			if err != nil {
+				t.Errorf("Error parsing YAML: %v", err)
+				return
+			}
+
+			path, err := NewPath(test.input)
+			if err != nil {
+				t.Errorf("Error parsing JSON Path: %v", err)
+				return
+			}
+
+			result := path.QueryWithPaths(&root)
+			var paths, parents, keys []string
+			var indices []int
+			for _, match := range result {
+				paths = append(paths, match.Path.String())
+				if match.Parent == nil {
+					parents = append(parents, "")
+				} else {
+					parents = append(parents, nodeToString(match.Parent))
+				}
+				keys = append(keys, match.Key)
+				indices = append(indices, match.Index)
+			}
+
+			if !reflect.DeepEqual(paths, test.expected) {
+				t.Errorf("Expected:\n%v\nGot:\n%v", test.expected, paths)
+			}
+			if !reflect.DeepEqual(parents, test.parents) {
+				t.Errorf("Expected parents:\n%v\nGot:\n%v", test.parents, parents)
+			}
+			if !reflect.DeepEqual(keys, test.keys) {
+				t.Errorf("Expected keys:\n%v\nGot:\n%v", test.keys, keys)
+			}
+			if !reflect.DeepEqual(indices, test.indices) {
+				t.Errorf("Expected indices:\n%v\nGot:\n%v", test.indices, indices)
+			}
+			if !reflect.DeepEqual(result.Nodes(), path.Query(&root)) {
+				t.Errorf("Expected QueryWithPaths nodes to equal Query")
+			}
+		})
+	}
+}
+
+func TestQueryWithPathsPropertyName(t *testing.T) {
+	var root yaml.Node
+	err := yaml.Unmarshal([]byte("components:\n  schemas:\n    Pet: {}\n    Tag: {}\n"), &root)
+	if err != nil {
+		t.Fatalf("Error parsing YAML: %v", err)
+	}
+	path, err := NewPath("$.components.schemas.*~", config.WithPropertyNameExtension())
+	if err != nil {
+		t.Fatalf("Error parsing JSON Path: %v", err)
+	}
+
+	result := path.QueryWithPaths(&root)
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(result))
+	}
+	for i, name := range []string{"Pet", "Tag"} {
+		match := result[i]
+		if !match.PropertyName || match.Node.Value != name || match.Key != name {
+			t.Errorf("Expected property name %q, got %+v", name, match)
+		}
+		if expected := "$['components']['schemas']['" + name + "']"; match.Path.String() != expected {
+			t.Errorf("Expected path %s, got %s", expected, match.Path.String())
+		}
+		if match.Parent.Kind != yaml.MappingNode || match.Parent.Content[2*i] != match.Node {
+			t.Errorf("Expected parent to be the schemas mapping")
+		}
+	}
+}
+
+func TestAll(t *testing.T) {
+	var root yaml.Node
+	err := yaml.Unmarshal([]byte(`
+store:
+  book:
+    - title: Book 1
+      price: 9.99
+    - title: Book 2
+      price: 12.99
+  bicycle:
+    price: 19.95
+`), &root)
+	if err != nil {
+		t.Fatalf("Error parsing YAML: %v", err)
+	}
+
+	for _, input := range []string{"$", "$..price", "$.store.*", "$..[?@.price > 10]", "$.store.book[::-1].title", "$..*"} {
+		t.Run(input, func(t *testing.T) {
+			path, err := NewPath(input)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			var actual []*yaml.Node
+			for node := range path.All(&root) {
+				actual = append(actual, node)
+			}
+			if !reflect.DeepEqual(actual, path.Query(&root)) {
+				t.Errorf("Expected All to yield the same nodes as Query")
+			}
+			var matches NodeList
+			for match := range path.AllWithPaths(&root) {
+				matches = append(matches, match)
+			}
+			if !reflect.DeepEqual(matches, path.QueryWithPaths(&root)) {
+				t.Errorf("Expected AllWithPaths to yield the same matches as QueryWithPaths")
+			}
+		})
+	}
+}
+
+func TestAllStopsEarly(t *testing.T) {
+	var root yaml.Node
+	err := yaml.Unmarshal([]byte(`[{a: 1}, {a: 2}, {a: 3}]`), &root)
+	if err != nil {
+		t.Fatalf("Error parsing YAML: %v", err)
+	}
+	// corrupt everything after the first element: visiting it would panic
+	seq := root.Content[0]
+	for _, child := range seq.Content[1:] {
+		child.Content = child.Content[:1]
+	}
+
+	path, err := NewPath("$..a")
+	if err != nil {
+		t.Fatalf("Error parsing JSON Path: %v", err)
+	}
+	var actual []string
+	for node := range path.All(&root) {
+		actual = append(actual, node.Value)
+		break
+	}
+	if !reflect.DeepEqual(actual, []string{"1"}) {
+		t.Errorf("Expected [1], got %v", actual)
+	}
+}
+
+func TestExistsFirstCount(t *testing.T) {
+	var root yaml.Node
+	err := yaml.Unmarshal([]byte(`
+paths:
+  /pets:
+    get: {operationId: listPets}
+    post: {operationId: createPet, deprecated: true}
+  /pets/{id}:
+    get: {operationId: getPet}
+`), &root)
+	if err != nil {
+		t.Fatalf("Error parsing YAML: %v", err)
+	}
+
+	tests := []struct {
+		input  string
+		exists bool
+		first  string
+		count  int
+	}{
+		{input: "$", exists: true, first: "", count: 1},
+		{input: "$.paths.*.*.operationId", exists: true, first: "listPets", count: 3},
+		{input: "$..[?@.deprecated].operationId", exists: true, first: "createPet", count: 1},
+		{input: "$..operationId", exists: true, first: "listPets", count: 3},
+		{input: "$.paths[?count(@.*) == 1].get.operationId", exists: true, first: "getPet", count: 1},
+		{input: "$.components", exists: false, count: 0},
+		{input: "$..[?@.summary]", exists: false, count: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			path, err := NewPath(test.input)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			if exists := path.Exists(&root); exists != test.exists {
+				t.Errorf("Expected Exists to be %v, got %v", test.exists, exists)
+			}
+			first := path.First(&root)
+			if !test.exists {
+				if first != nil {
+					t.Errorf("Expected no first node, got %v", first)
+				}
+			} else if first == nil || first.Value != test.first {
+				t.Errorf("Expected first node %q, got %v", test.first, first)
+			}
+			if count := path.Count(&root); count != test.count {
+				t.Errorf("Expected Count to be %d, got %d", test.count, count)
+			}
+		})
+	}
+}
+
+func TestExistsShortCircuits(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "descendant segment", input: "$..a"},
+		{name: "filter selector", input: "$[?@.a]"},
+		{name: "existence test with descendant segment", input: "$[?@..a]"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var root yaml.Node
+			err := yaml.Unmarshal([]byte(`[{a: 1}, {a: 2}, {a: 3}]`), &root)
+			if err != nil {
+				t.Fatalf("Error parsing YAML: %v", err)
+			}
+			// corrupt everything after the first element: visiting it would panic
+			for _, child := range root.Content[0].Content[1:] {
+				child.Content = child.Content[:1]
+			}
+
+			path, err := NewPath(test.input)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			if !path.Exists(&root) {
+				t.Errorf("Expected a match")
+			}
+			if path.First(&root) == nil {
+				t.Errorf("Expected a first match")
+			}
+		})
+	}
+}
+
+func TestTagResolvers(t *testing.T) {
+	const document = `
+- {name: a, created: 2023-12-31T22:00:00-02:00, ref: !Ref Bucket, sub: !Sub Bucket, size: !Size 2k}
+- {name: b, created: 2024-01-01, ref: Bucket, size: 1024}
+- {name: c, created: 2024-06-01 12:00:00, ref: !Ref Queue, sub: !Ref Queue, size: !Size 512}
+- {name: d, created: "2025-01-01"}
+`
+	size := func(node *yaml.Node) (any, bool) {
+		if n, ok := strings.CutSuffix(node.Value, "k"); ok {
+			i, err := strconv.Atoi(n)
+			return i * 1024, err == nil
+		}
+		i, err := strconv.Atoi(node.Value)
+		return i, err == nil
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		opts     []config.Option
+		expected []string
+	}{
+		{
+			name:     "Timestamps compare chronologically",
+			input:    "$[?@.created > '2024-01-01'].name",
+			expected: []string{"c", "d"},
+		},
+		{
+			name:     "Timestamps equal strings holding the same time",
+			input:    "$[?@.created == '2024-01-01T00:00:00Z'].name",
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "Strings compare as strings",
+			input:    "$[?@.created >= '2025'].name",
+			expected: []string{"d"},
+		},
+		{
+			name:     "Timestamps compare with each other",
+			input:    "$[?@.created == $[1].created].name",
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "Timestamp resolution disabled",
+			input:    "$[?@.created > '2024-01-01'].name",
+			opts:     []config.Option{config.WithTagResolver("!!timestamp", nil)},
+			expected: []string{"d"},
+		},
+		{
+			name:     "Custom tags only equal the same tag and value without a resolver",
+			input:    "$[?@.ref == $[0].ref].name",
+			expected: []string{"a"},
+		},
+		{
+			name:     "Custom tags with the same value but different tags differ",
+			input:    "$[?@.sub && @.ref == @.sub].name",
+			expected: []string{"c"},
+		},
+		{
+			name:  "Custom tags resolved to the same string are equal",
+			input: "$[?@.sub && @.ref == @.sub].name",
+			opts: []config.Option{
+				config.WithTagResolver("!Ref", func(node *yaml.Node) (any, bool) { return node.Value, true }),
+				config.WithTagResolver("!Sub", func(node *yaml.Node) (any, bool) { return node.Value, true }),
+			},
+			expected: []string{"a", "c"},
+		},
+		{
+			name:     "Custom tag resolved to a string",
+			input:    "$[?@.ref == 'Bucket'].name",
+			opts:     []config.Option{config.WithTagResolver("!Ref", func(node *yaml.Node) (any, bool) { return node.Value, true })},
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "Custom tag resolved to a number",
+			input:    "$[?@.size >= 1024].name",
+			opts:     []config.Option{config.WithTagResolver("!Size", size)},
+			expected: []string{"a", "b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte(document), &root); err != nil {
+				t.Fatal(err)
+			}
+			path, err := NewPath(test.input, test.opts...)
+			if err != nil {
+				t.Fatalf("Error parsing JSON Path: %v", err)
+			}
+			actual := []string{}
+			for _, node := range path.Query(&root) {
+				actual = append(actual, node.Value)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("Expected:\n%v\nGot:\n%v", test.expected, actual)
+			}
+		})
+	}
+}